@@ -0,0 +1,6 @@
+// Package orderv1 holds the protobuf definition of the order gRPC API and
+// the Go code generated from it, including the OrderServiceClient used by
+// internal callers.
+package orderv1
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative order.proto
@@ -0,0 +1,6 @@
+package auth
+
+import "time"
+
+// SetClock replaces the clock that decides when the key set is refreshed.
+func (j *JWKS) SetClock(now func() time.Time) { j.now = now }
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrUnknownKey is returned when no key in the set matches a token's kid.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// minRefreshInterval spaces out refresh attempts, successful or not, so
+// that tokens with made-up kids cannot hammer the JWKS endpoint and a down
+// endpoint is not retried on every request.
+const minRefreshInterval = 30 * time.Second
+
+// JWKS is a JSON Web Key Set loaded from a file path or an http(s) URL. It
+// is refreshed every refreshInterval and when a token names a key ID the set
+// does not contain, so key rotation needs no restart. Known keys are served
+// from memory while a refresh runs in the background; only unknown key IDs
+// wait for one. Concurrent refreshes are coalesced.
+type JWKS struct {
+	source          string
+	refreshInterval time.Duration
+	client          *http.Client
+	group           singleflight.Group
+	now             func() time.Time
+
+	mutex       sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewJWKS loads the key set once and fails if it cannot be read.
+func NewJWKS(ctx context.Context, source string, refreshInterval time.Duration) (*JWKS, error) {
+	if refreshInterval <= 0 {
+		return nil, errors.New("JWKS refresh interval must be positive")
+	}
+	j := &JWKS{
+		source:          source,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		now:             time.Now,
+	}
+	if err := j.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Key returns the public key with the given key ID.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	now := j.now()
+	j.mutex.RLock()
+	key, ok := j.keys[kid]
+	age := now.Sub(j.fetchedAt)
+	sinceAttempt := now.Sub(j.attemptedAt)
+	j.mutex.RUnlock()
+
+	if ok {
+		if age >= j.refreshInterval && sinceAttempt >= min(j.refreshInterval, minRefreshInterval) {
+			j.group.DoChan("refresh", j.backgroundRefresh)
+		}
+		return key, nil
+	}
+	if sinceAttempt < minRefreshInterval {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+
+	select {
+	case <-j.group.DoChan("refresh", j.backgroundRefresh):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// backgroundRefresh is detached from the request that triggered it, since
+// other requests may be waiting on it too.
+func (j *JWKS) backgroundRefresh() (interface{}, error) {
+	err := j.refresh(context.Background())
+	if err != nil {
+		slog.Warn("JWKS refresh FAILED", "source", j.source, "error", err)
+	}
+	return nil, err
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	j.mutex.Lock()
+	j.attemptedAt = j.now()
+	j.mutex.Unlock()
+
+	data, err := j.read(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	j.keys = keys
+	j.fetchedAt = j.now()
+	j.mutex.Unlock()
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes the RSA and EC signing keys of a key set, skipping keys
+// of other types or uses.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsa()
+		case "EC":
+			key, err = k.ecdsa()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWK %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsa() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() {
+		return nil, errors.New("RSA exponent too large")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecdsa() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,137 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+)
+
+// jwksServer serves one EC key and counts requests. Once failing is set it
+// answers 503 after waiting for release.
+type jwksServer struct {
+	*httptest.Server
+	hits    atomic.Int32
+	failing atomic.Bool
+	release chan struct{}
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(key.X), "y": b64(key.Y)},
+	}})
+
+	s := &jwksServer{release: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.failing.Load() {
+			<-s.release
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+type clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+func TestJWKS_UnknownKidsWhileSourceIsDown(t *testing.T) {
+	srv := newJWKSServer(t)
+	ctx := context.Background()
+	jwks, err := auth.NewJWKS(ctx, srv.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &clock{now: time.Now()}
+	jwks.SetClock(clk.Now)
+
+	srv.failing.Store(true)
+	clk.Advance(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jwks.Key(ctx, "made-up"); !errors.Is(err, auth.ErrUnknownKey) {
+				t.Errorf("Expected ErrUnknownKey, got %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(srv.release)
+	wg.Wait()
+
+	if n := srv.hits.Load(); n != 2 {
+		t.Fatalf("Expected concurrent misses to share one refresh, got %d requests", n)
+	}
+	if _, err := jwks.Key(ctx, "made-up"); !errors.Is(err, auth.ErrUnknownKey) {
+		t.Fatalf("Expected ErrUnknownKey, got %v", err)
+	}
+	if n := srv.hits.Load(); n != 2 {
+		t.Errorf("Expected a failed refresh to hold off the next one, got %d requests", n)
+	}
+}
+
+func TestJWKS_KnownKeyDoesNotWaitForRefresh(t *testing.T) {
+	srv := newJWKSServer(t)
+	ctx := context.Background()
+	jwks, err := auth.NewJWKS(ctx, srv.URL, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &clock{now: time.Now()}
+	jwks.SetClock(clk.Now)
+
+	srv.failing.Store(true)
+	defer close(srv.release)
+	clk.Advance(2 * time.Minute)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := jwks.Key(ctx, "ec-1"); err != nil {
+			t.Fatalf("Expected the cached key, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("known key waited %v for the refresh", elapsed)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.hits.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := srv.hits.Load(); n != 2 {
+		t.Errorf("Expected one background refresh, got %d requests", n-1)
+	}
+}
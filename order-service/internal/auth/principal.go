@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scopes understood by the order API.
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	// ScopeOrdersAdmin lets staff read the orders of every customer.
+	ScopeOrdersAdmin = "orders:admin"
+	// ScopeWebhooksManage lets a tenant manage its webhooks.
+	ScopeWebhooksManage = "webhooks:manage"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Claims  jwt.MapClaims
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessCustomer reports whether the principal may read the orders of
+// customerID: its own, or anyone's with the admin scope.
+func (p *Principal) CanAccessCustomer(customerID string) bool {
+	return p.Subject == customerID || p.HasScope(ScopeOrdersAdmin)
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal of the request, or nil when the request
+// was not authenticated.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(ctxKey{}).(*Principal)
+	return p
+}
+
+// scopesOf reads the space separated "scope" claim (RFC 8693) or, failing
+// that, the "scp" claim as a string or list.
+func scopesOf(claims jwt.MapClaims) []string {
+	for _, name := range []string{"scope", "scp"} {
+		switch v := claims[name].(type) {
+		case string:
+			return strings.Fields(v)
+		case []interface{}:
+			scopes := make([]string, 0, len(v))
+			for _, s := range v {
+				if str, ok := s.(string); ok {
+					scopes = append(scopes, str)
+				}
+			}
+			return scopes
+		}
+	}
+	return nil
+}
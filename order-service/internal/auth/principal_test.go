@@ -0,0 +1,22 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+)
+
+func TestPrincipal_CanAccessCustomer(t *testing.T) {
+	customer := &auth.Principal{Subject: "alice", Scopes: []string{auth.ScopeOrdersRead}}
+	if !customer.CanAccessCustomer("alice") {
+		t.Error("Expected a customer to read their own orders")
+	}
+	if customer.CanAccessCustomer("bob") {
+		t.Error("Expected a customer not to read another customer's orders")
+	}
+
+	admin := &auth.Principal{Subject: "support", Scopes: []string{auth.ScopeOrdersRead, auth.ScopeOrdersAdmin}}
+	if !admin.CanAccessCustomer("bob") {
+		t.Error("Expected the admin scope to read any customer's orders")
+	}
+}
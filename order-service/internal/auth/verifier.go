@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Config struct {
+	// HS256Secret enables HS256 tokens signed with this shared secret.
+	HS256Secret string
+	// JWKS enables RS256 and ES256 tokens signed by a key of the set.
+	JWKS *JWKS
+
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// Verifier validates bearer tokens.
+type Verifier struct {
+	cfg     Config
+	methods []string
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	var methods []string
+	if cfg.HS256Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKS != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth needs an HS256 secret or a JWKS")
+	}
+	return &Verifier{cfg: cfg, methods: methods}, nil
+}
+
+// Verify checks the signature, expiry and, when configured, issuer and
+// audience of token and returns its principal.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithLeeway(v.cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+			return []byte(v.cfg.HS256Secret), nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.cfg.JWKS.Key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	return &Principal{Subject: sub, Scopes: scopesOf(claims), Claims: claims}, nil
+}
@@ -0,0 +1,110 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func claims(sub string, exp time.Duration) jwt.MapClaims {
+	return jwt.MapClaims{"sub": sub, "scope": "orders:read orders:write", "exp": time.Now().Add(exp).Unix()}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	v, err := auth.NewVerifier(auth.Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	p, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte("secret"), "", claims("customer-1", time.Minute)))
+	if err != nil {
+		t.Fatalf("Expected valid token, got %v", err)
+	}
+	if p.Subject != "customer-1" || !p.HasScope(auth.ScopeOrdersWrite) {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte("secret"), "", claims("c", -time.Hour))); err == nil {
+		t.Error("Expected expired token to be rejected")
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims("c", time.Minute))); err == nil {
+		t.Error("Expected token with wrong signature to be rejected")
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS384, []byte("secret"), "", claims("c", time.Minute))); err == nil {
+		t.Error("Expected HS384 token to be rejected")
+	}
+}
+
+func b64(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+func TestVerifier_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+	}}
+	data, _ := json.Marshal(set)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	jwks, err := auth.NewJWKS(ctx, file, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := auth.NewVerifier(auth.Config{JWKS: jwks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims("a", time.Minute))); err != nil {
+		t.Errorf("Expected RS256 token to verify, got %v", err)
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodES256, ecKey, "ec-1", claims("b", time.Minute))); err != nil {
+		t.Errorf("Expected ES256 token to verify, got %v", err)
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, rsaKey, "unknown", claims("c", time.Minute))); err == nil {
+		t.Error("Expected token with unknown kid to be rejected")
+	}
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte("x"), "", claims("d", time.Minute))); err == nil {
+		t.Error("Expected HS256 token to be rejected without a secret")
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const redacted = "******"
+
+// Dump renders the effective configuration as ENV=value lines with
+// secrets redacted, suitable for logging at startup.
+func (c *Config) Dump() string {
+	cp := *c
+	var b strings.Builder
+	for _, f := range collect(reflect.ValueOf(&cp).Elem()) {
+		fmt.Fprintf(&b, "%s=%s\n", f.env, f.display())
+	}
+	return b.String()
+}
+
+func (f field) display() string {
+	var raw string
+	switch v := f.value.Interface().(type) {
+	case []string:
+		raw = strings.Join(v, ",")
+	default:
+		raw = fmt.Sprint(v)
+	}
+
+	switch {
+	case f.secret == "" || raw == "":
+		return raw
+	case f.secret == "url":
+		// keep the URL readable, hide only the password
+		u, err := url.Parse(raw)
+		if err != nil {
+			return redacted
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		return u.String()
+	default:
+		return redacted
+	}
+}
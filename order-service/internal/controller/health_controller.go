@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/health"
+	"github.com/gorilla/mux"
+)
+
+type HealthController struct {
+	Checker *health.Checker
+}
+
+func NewHealthController(c *health.Checker) *HealthController {
+	return &HealthController{Checker: c}
+}
+
+func (c *HealthController) Routes(r *mux.Router) {
+	r.HandleFunc("/healthz", c.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", c.Readiness).Methods("GET")
+}
+
+// Liveness only reports that the process is serving HTTP.
+func (c *HealthController) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusUp})
+}
+
+// Readiness checks every registered dependency.
+func (c *HealthController) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Checker.Run(r.Context())
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
@@ -1,64 +1,138 @@
 package controller
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/product"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/validation"
 	"github.com/gorilla/mux"
 )
 
 type OrderController struct {
 	Service *service.OrderService
+	Auth    *middleware.Auth
+	Version APIVersion
 }
 
-func NewOrderController(s *service.OrderService) *OrderController {
-	return &OrderController{Service: s}
+func NewOrderController(s *service.OrderService, a *middleware.Auth, v APIVersion) *OrderController {
+	return &OrderController{Service: s, Auth: a, Version: v}
 }
 
 func (c *OrderController) Routes(r *mux.Router) {
-	r.HandleFunc("/orders", c.CreateOrder).Methods("POST")
-	r.HandleFunc("/orders/product/{id}", c.GetOrdersByProduct).Methods("GET")
+	write := c.Auth.Require(auth.ScopeOrdersWrite)
+	read := c.Auth.Require(auth.ScopeOrdersRead)
+
+	r.Handle("/orders", write(http.HandlerFunc(c.CreateOrder))).Methods("POST")
+	r.Handle("/orders/product/{id}", read(http.HandlerFunc(c.GetOrdersByProduct))).Methods("GET")
+	r.Handle("/customers/{id}/orders", read(http.HandlerFunc(c.GetOrdersByCustomer))).Methods("GET")
+	r.Handle("/me/orders", read(http.HandlerFunc(c.GetMyOrders))).Methods("GET")
+	r.Handle("/orders/{id}/events", read(http.HandlerFunc(c.GetOrderEvents))).Methods("GET")
+	r.Handle("/customers/{id}/orders/events", read(http.HandlerFunc(c.GetCustomerOrderEvents))).Methods("GET")
+	r.Handle("/me/orders/events", read(http.HandlerFunc(c.GetMyOrderEvents))).Methods("GET")
 }
 
 func (c *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
 	w.Header().Set("X-Request-ID", requestID)
-	w.Header().Set("Content-Type", "application/json")
 
-	var req struct {
-		ProductID int `json:"productId"`
-		Quantity  int `json:"quantity"`
+	var req domain.CreateOrderDTO
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if errs := req.Validate(); errs.HasErrors() {
+		writeValidationError(w, errs)
 		return
 	}
 
-	order, err := c.Service.CreateOrder(r.Context(), req.ProductID, req.Quantity)
+	var customerID string
+	if p := auth.FromContext(r.Context()); p != nil {
+		customerID = p.Subject
+	}
+
+	order, err := c.Service.CreateOrder(r.Context(), customerID, req.ProductID, req.Quantity)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, product.ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, product.ErrUnavailable):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		case errors.Is(err, service.ErrOverloaded):
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	json.NewEncoder(w).Encode(order)
+	writeJSON(w, http.StatusOK, renderOrder(c.Version, order))
 }
 
 func (c *OrderController) GetOrdersByProduct(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
 	w.Header().Set("X-Request-ID", requestID)
-	w.Header().Set("Content-Type", "application/json")
 
-	idStr := mux.Vars(r)["id"]
-	id, _ := strconv.Atoi(idStr)
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
 
-	orders, err := c.Service.GetOrdersByProductID(id)
+	orders, err := c.Service.GetOrdersByProductID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	json.NewEncoder(w).Encode(orders)
+	// customers only see their own orders of the product
+	if p := auth.FromContext(r.Context()); p != nil && !p.HasScope(auth.ScopeOrdersAdmin) {
+		own := make([]*domain.Order, 0, len(orders))
+		for _, o := range orders {
+			if o.CustomerID == p.Subject {
+				own = append(own, o)
+			}
+		}
+		orders = own
+	}
+
+	writeJSON(w, http.StatusOK, renderOrders(c.Version, orders))
+}
+
+func (c *OrderController) GetOrdersByCustomer(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	customerID := mux.Vars(r)["id"]
+	if p := auth.FromContext(r.Context()); p != nil && !p.CanAccessCustomer(customerID) {
+		writeError(w, http.StatusForbidden, "orders of another customer")
+		return
+	}
+	c.writeCustomerOrders(w, r, customerID)
+}
+
+func (c *OrderController) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	c.writeCustomerOrders(w, r, p.Subject)
+}
+
+func (c *OrderController) writeCustomerOrders(w http.ResponseWriter, r *http.Request, customerID string) {
+	orders, err := c.Service.GetOrdersByCustomerID(r.Context(), customerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, renderOrders(c.Version, orders))
 }
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+)
+
+// APIVersion selects the response shapes of a route group.
+type APIVersion int
+
+const (
+	// VersionLegacy is the deprecated unversioned API at the root.
+	VersionLegacy APIVersion = iota
+	V1
+)
+
+// legacyOrder keeps the field names the unversioned routes returned when
+// they serialized domain.Order directly.
+type legacyOrder struct {
+	ID         int       `json:"ID"`
+	TenantID   string    `json:"TenantID"`
+	ProductID  int       `json:"ProductID"`
+	CustomerID string    `json:"CustomerID"`
+	TotalPrice float64   `json:"TotalPrice"`
+	Status     string    `json:"Status"`
+	CreatedAt  time.Time `json:"CreatedAt"`
+}
+
+type orderV1 struct {
+	ID         int              `json:"id"`
+	ProductID  int              `json:"productId"`
+	CustomerID string           `json:"customerId"`
+	TotalPrice float64          `json:"totalPrice"`
+	Status     string           `json:"status"`
+	CreatedAt  domain.Timestamp `json:"createdAt"`
+}
+
+func renderOrder(v APIVersion, o *domain.Order) interface{} {
+	if v == VersionLegacy {
+		return legacyOrder{
+			ID:         o.ID,
+			TenantID:   o.TenantID,
+			ProductID:  o.ProductID,
+			CustomerID: o.CustomerID,
+			TotalPrice: o.TotalPrice,
+			Status:     o.Status,
+			CreatedAt:  o.CreatedAt,
+		}
+	}
+	return orderV1{
+		ID:         o.ID,
+		ProductID:  o.ProductID,
+		CustomerID: o.CustomerID,
+		TotalPrice: o.TotalPrice,
+		Status:     o.Status,
+		CreatedAt:  domain.Timestamp(o.CreatedAt),
+	}
+}
+
+func renderOrders(v APIVersion, orders []*domain.Order) []interface{} {
+	out := make([]interface{}, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, renderOrder(v, o))
+	}
+	return out
+}
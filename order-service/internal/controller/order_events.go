@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// orderEventResponse is the data of an SSE order.status event.
+type orderEventResponse struct {
+	// ID is the seq of the event, which is also its SSE event ID.
+	ID         int64            `json:"id"`
+	OrderID    int              `json:"orderId"`
+	CustomerID string           `json:"customerId,omitempty"`
+	Status     string           `json:"status"`
+	CreatedAt  domain.Timestamp `json:"createdAt"`
+}
+
+// GetOrderEvents streams the status changes of one order.
+func (c *OrderController) GetOrderEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	order, err := c.Service.GetOrder(r.Context(), id)
+	if errors.Is(err, service.ErrOrderNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if p := auth.FromContext(r.Context()); p != nil && !p.CanAccessCustomer(order.CustomerID) {
+		writeError(w, http.StatusForbidden, "order of another customer")
+		return
+	}
+	c.streamEvents(w, r, service.EventFilter{OrderID: order.ID})
+}
+
+// GetCustomerOrderEvents streams the status changes of a customer's orders.
+func (c *OrderController) GetCustomerOrderEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	customerID := mux.Vars(r)["id"]
+	if p := auth.FromContext(r.Context()); p != nil && !p.CanAccessCustomer(customerID) {
+		writeError(w, http.StatusForbidden, "orders of another customer")
+		return
+	}
+	c.streamEvents(w, r, service.EventFilter{CustomerID: customerID})
+}
+
+// GetMyOrderEvents streams the status changes of the caller's orders.
+func (c *OrderController) GetMyOrderEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	c.streamEvents(w, r, service.EventFilter{CustomerID: p.Subject})
+}
+
+// streamEvents writes the events matching filter as Server-Sent Events,
+// resuming after the Last-Event-ID header when the client reconnects.
+func (c *OrderController) streamEvents(w http.ResponseWriter, r *http.Request, filter service.EventFilter) {
+	var afterSeq int64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			writeError(w, http.StatusBadRequest, "Last-Event-ID must be a non-negative integer")
+			return
+		}
+		afterSeq = id
+	}
+
+	rc := http.NewResponseController(w)
+	// streams outlive HTTP_WRITE_TIMEOUT
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	err := c.Service.StreamOrderEvents(r.Context(), filter, afterSeq, func(ev *domain.OrderEvent) error {
+		if ev == nil {
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		data, err := json.Marshal(orderEventResponse{
+			ID:         ev.Seq,
+			OrderID:    ev.OrderID,
+			CustomerID: ev.CustomerID,
+			Status:     ev.Status,
+			CreatedAt:  domain.Timestamp(ev.CreatedAt),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: order.status\ndata: %s\n\n", ev.Seq, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err != nil && r.Context().Err() == nil {
+		logging.FromContext(r.Context()).Error("Order event stream FAILED", "error", err)
+	}
+}
@@ -1,13 +1,41 @@
 package controller
 
 import (
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/health"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/openapi"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(s *service.OrderService) *mux.Router {
+// NewRouter wires every route. Health, metrics and docs endpoints are public;
+// the order API requires authentication unless a is nil or has no verifier,
+// is throttled per IP address by rl ahead of authentication and per client
+// after it, and is scoped to the tenant resolved by t.
+// The API is served under /v1 and, unless legacy is nil, also at the root
+// with the original response shapes and deprecation headers.
+func NewRouter(s *service.OrderService, checker *health.Checker, a *middleware.Auth, rl *middleware.RateLimit, t *middleware.Tenant, legacy *middleware.Deprecation) *mux.Router {
 	r := mux.NewRouter()
-	ctrl := NewOrderController(s)
-	ctrl.Routes(r)
+	r.Use(middleware.TracingMiddleware, middleware.AccessLogMiddleware, middleware.MetricsMiddleware)
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	r.Handle("/openapi.json", openapi.Handler()).Methods("GET")
+	// the page and its assets below /docs/
+	r.PathPrefix("/docs").Handler(openapi.DocsHandler()).Methods("GET")
+	NewHealthController(checker).Routes(r)
+
+	api := r.NewRoute().Subrouter()
+	api.Use(rl.PerIP, a.Authenticate, rl.Middleware, t.Middleware)
+
+	v1 := api.PathPrefix("/v1").Subrouter()
+	NewOrderController(s, a, V1).Routes(v1)
+	NewWebhookController(s, a).Routes(v1)
+
+	if legacy != nil {
+		root := api.NewRoute().Subrouter()
+		root.Use(legacy.Middleware)
+		NewOrderController(s, a, VersionLegacy).Routes(root)
+		NewWebhookController(s, a).Routes(root)
+	}
 	return r
 }
@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/validation"
+	"github.com/gorilla/mux"
+)
+
+// Page sizes of the delivery log.
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+)
+
+type WebhookController struct {
+	Service *service.OrderService
+	Auth    *middleware.Auth
+}
+
+func NewWebhookController(s *service.OrderService, a *middleware.Auth) *WebhookController {
+	return &WebhookController{Service: s, Auth: a}
+}
+
+func (c *WebhookController) Routes(r *mux.Router) {
+	manage := c.Auth.Require(auth.ScopeWebhooksManage)
+
+	r.Handle("/webhooks", manage(http.HandlerFunc(c.CreateWebhook))).Methods("POST")
+	r.Handle("/webhooks", manage(http.HandlerFunc(c.ListWebhooks))).Methods("GET")
+	r.Handle("/webhooks/{id}", manage(http.HandlerFunc(c.GetWebhook))).Methods("GET")
+	r.Handle("/webhooks/{id}", manage(http.HandlerFunc(c.UpdateWebhook))).Methods("PATCH")
+	r.Handle("/webhooks/{id}", manage(http.HandlerFunc(c.DeleteWebhook))).Methods("DELETE")
+	r.Handle("/webhooks/{id}/deliveries", manage(http.HandlerFunc(c.ListDeliveries))).Methods("GET")
+}
+
+// webhookResponse never carries the secret except right after creation.
+type webhookResponse struct {
+	ID                  int               `json:"id"`
+	URL                 string            `json:"url"`
+	Events              []string          `json:"events"`
+	Active              bool              `json:"active"`
+	ConsecutiveFailures int               `json:"consecutiveFailures"`
+	DisabledAt          *domain.Timestamp `json:"disabledAt,omitempty"`
+	CreatedAt           domain.Timestamp  `json:"createdAt"`
+	UpdatedAt           domain.Timestamp  `json:"updatedAt"`
+	Secret              string            `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(w *domain.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:                  w.ID,
+		URL:                 w.URL,
+		Events:              w.Events,
+		Active:              w.Active,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		DisabledAt:          domain.NewTimestampPtr(w.DisabledAt),
+		CreatedAt:           domain.Timestamp(w.CreatedAt),
+		UpdatedAt:           domain.Timestamp(w.UpdatedAt),
+	}
+}
+
+type deliveryResponse struct {
+	ID             int64             `json:"id"`
+	Event          string            `json:"event"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  *domain.Timestamp `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *domain.Timestamp `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int               `json:"responseStatus,omitempty"`
+	LastError      string            `json:"lastError,omitempty"`
+	Payload        json.RawMessage   `json:"payload"`
+	CreatedAt      domain.Timestamp  `json:"createdAt"`
+}
+
+func newDeliveryResponse(d *domain.WebhookDelivery) deliveryResponse {
+	resp := deliveryResponse{
+		ID:             d.ID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  domain.NewTimestampPtr(d.LastAttemptAt),
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		Payload:        d.Payload,
+		CreatedAt:      domain.Timestamp(d.CreatedAt),
+	}
+	if d.Status == domain.DeliveryPending {
+		resp.NextAttemptAt = domain.NewTimestampPtr(&d.NextAttemptAt)
+	}
+	return resp
+}
+
+func (c *WebhookController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	var req domain.CreateWebhookDTO
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errs := req.Validate(); errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	hook, err := c.Service.CreateWebhook(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := newWebhookResponse(hook)
+	resp.Secret = hook.Secret
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (c *WebhookController) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	hooks, err := c.Service.ListWebhooks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		resp = append(resp, newWebhookResponse(hook))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (c *WebhookController) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	hook, err := c.Service.GetWebhook(r.Context(), id)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newWebhookResponse(hook))
+}
+
+func (c *WebhookController) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	var req domain.UpdateWebhookDTO
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errs := req.Validate(); errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	hook, err := c.Service.UpdateWebhook(r.Context(), id, req)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newWebhookResponse(hook))
+}
+
+func (c *WebhookController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	if err := c.Service.DeleteWebhook(r.Context(), id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries serves the delivery log of a webhook, newest first. It
+// takes an optional status filter and pages with limit and before, the
+// lowest delivery ID of the previous page.
+func (c *WebhookController) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	w.Header().Set("X-Request-ID", requestID)
+
+	id, errs := validation.ParsePositiveInt("id", mux.Vars(r)["id"])
+	query := r.URL.Query()
+
+	status := query.Get("status")
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliverySucceeded, domain.DeliveryFailed:
+	default:
+		errs.Add("status", "must be one of pending, succeeded, failed")
+	}
+
+	limit := DefaultDeliveryLimit
+	if raw := query.Get("limit"); raw != "" {
+		var limitErrs validation.Errors
+		limit, limitErrs = validation.ParsePositiveInt("limit", raw)
+		errs = append(errs, limitErrs...)
+		if limit > MaxDeliveryLimit {
+			errs.Add("limit", "must not exceed "+strconv.Itoa(MaxDeliveryLimit))
+		}
+	}
+
+	var before int64
+	if raw := query.Get("before"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v <= 0 {
+			errs.Add("before", "must be a positive integer")
+		}
+		before = v
+	}
+
+	if errs.HasErrors() {
+		writeValidationError(w, errs)
+		return
+	}
+
+	deliveries, err := c.Service.ListWebhookDeliveries(r.Context(), id, status, before, limit)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	resp := make([]deliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, newDeliveryResponse(d))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
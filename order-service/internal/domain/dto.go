@@ -1,6 +1,83 @@
 package domain
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/validation"
+)
+
+const (
+	MinOrderQuantity = 1
+	MaxOrderQuantity = 1000
+)
+
 type CreateOrderDTO struct {
 	ProductID int `json:"productId"`
 	Quantity  int `json:"quantity"`
 }
+
+// Validate checks the DTO and returns the list of invalid fields, if any.
+func (d CreateOrderDTO) Validate() validation.Errors {
+	var errs validation.Errors
+	errs.PositiveInt("productId", d.ProductID)
+	errs.IntRange("quantity", d.Quantity, MinOrderQuantity, MaxOrderQuantity)
+	return errs
+}
+
+// MinWebhookSecretLength is the shortest signing secret a client may choose.
+const MinWebhookSecretLength = 16
+
+type CreateWebhookDTO struct {
+	URL string `json:"url"`
+	// Events defaults to every webhook event.
+	Events []string `json:"events"`
+	// Secret is generated when empty.
+	Secret string `json:"secret"`
+}
+
+// Validate checks the DTO and returns the list of invalid fields, if any.
+func (d CreateWebhookDTO) Validate() validation.Errors {
+	var errs validation.Errors
+	errs.HTTPURL("url", d.URL)
+	if d.Events != nil {
+		validateWebhookEvents(&errs, d.Events)
+	}
+	if d.Secret != "" && len(d.Secret) < MinWebhookSecretLength {
+		errs.Add("secret", fmt.Sprintf("must be at least %d characters", MinWebhookSecretLength))
+	}
+	return errs
+}
+
+// UpdateWebhookDTO changes the fields that are set. Setting Active to true
+// re-enables a webhook disabled after repeated failures.
+type UpdateWebhookDTO struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// Validate checks the DTO and returns the list of invalid fields, if any.
+func (d UpdateWebhookDTO) Validate() validation.Errors {
+	var errs validation.Errors
+	if d.URL != nil {
+		errs.HTTPURL("url", *d.URL)
+	}
+	if d.Events != nil {
+		validateWebhookEvents(&errs, d.Events)
+	}
+	return errs
+}
+
+func validateWebhookEvents(errs *validation.Errors, events []string) {
+	if len(events) == 0 {
+		errs.Add("events", "must not be empty")
+		return
+	}
+	for _, e := range events {
+		if !slices.Contains(WebhookEvents, e) {
+			errs.Add("events", fmt.Sprintf("unknown event %q, expected one of %s", e, strings.Join(WebhookEvents, ", ")))
+		}
+	}
+}
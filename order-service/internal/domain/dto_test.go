@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/validation"
+)
+
+func TestCreateOrderDTO_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		dto    domain.CreateOrderDTO
+		fields []string
+	}{
+		{"valid", domain.CreateOrderDTO{ProductID: 1, Quantity: 2}, nil},
+		{"zero product", domain.CreateOrderDTO{ProductID: 0, Quantity: 1}, []string{"productId"}},
+		{"negative quantity", domain.CreateOrderDTO{ProductID: 1, Quantity: -1}, []string{"quantity"}},
+		{"quantity too large", domain.CreateOrderDTO{ProductID: 1, Quantity: domain.MaxOrderQuantity + 1}, []string{"quantity"}},
+		{"both invalid", domain.CreateOrderDTO{}, []string{"productId", "quantity"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.dto.Validate()
+			if len(errs) != len(tc.fields) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tc.fields), len(errs), errs)
+			}
+			for i, f := range tc.fields {
+				if errs[i].Field != f {
+					t.Errorf("Expected error on %s, got %s", f, errs[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDTO_Validate(t *testing.T) {
+	valid := "https://partner.example.com/hooks"
+	invalid := "ftp://partner.example.com"
+	cases := []struct {
+		name   string
+		errs   validation.Errors
+		fields []string
+	}{
+		{"create valid", domain.CreateWebhookDTO{URL: valid}.Validate(), nil},
+		{"create bad url", domain.CreateWebhookDTO{URL: "/hooks"}.Validate(), []string{"url"}},
+		{"create unknown event", domain.CreateWebhookDTO{URL: valid, Events: []string{"order.shipped"}}.Validate(), []string{"events"}},
+		{"create empty events", domain.CreateWebhookDTO{URL: valid, Events: []string{}}.Validate(), []string{"events"}},
+		{"create short secret", domain.CreateWebhookDTO{URL: valid, Secret: "short"}.Validate(), []string{"secret"}},
+		{"update nothing", domain.UpdateWebhookDTO{}.Validate(), nil},
+		{"update bad url", domain.UpdateWebhookDTO{URL: &invalid}.Validate(), []string{"url"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.errs) != len(tc.fields) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tc.fields), len(tc.errs), tc.errs)
+			}
+			for i, f := range tc.fields {
+				if tc.errs[i].Field != f {
+					t.Errorf("Expected error on %s, got %s", f, tc.errs[i].Field)
+				}
+			}
+		})
+	}
+}
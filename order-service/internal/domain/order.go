@@ -2,10 +2,35 @@ package domain
 
 import "time"
 
+// Order statuses. Cancelled orders never change status again.
+const (
+	OrderStatusWaiting   = "waiting"
+	OrderStatusDone      = "done"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Order.Revision starts at 1 and grows with every status change, so cached
+// copies can tell which of two versions of an order is newer.
 type Order struct {
 	ID         int       `db:"id"`
+	TenantID   string    `db:"tenant_id"`
 	ProductID  int       `db:"product_id"`
+	CustomerID string    `db:"customer_id"`
 	TotalPrice float64   `db:"total_price"`
 	Status     string    `db:"status"`
 	CreatedAt  time.Time `db:"created_at"`
+	Revision   int64     `db:"revision"`
+}
+
+// OrderEvent records one status an order entered. Seq numbers the events
+// of a tenant in the order they were committed, so it doubles as the stream
+// position; IDs are unique but may commit out of order.
+type OrderEvent struct {
+	ID         int64     `db:"id"`
+	Seq        int64     `db:"seq"`
+	TenantID   string    `db:"tenant_id"`
+	OrderID    int       `db:"order_id"`
+	CustomerID string    `db:"customer_id"`
+	Status     string    `db:"status"`
+	CreatedAt  time.Time `db:"created_at"`
 }
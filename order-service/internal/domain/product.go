@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Product is the order-service's read model of a product owned by the
+// product-service. It is kept up to date from product.* events.
+type Product struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Price     float64   `json:"price" db:"price"`
+	Qty       int       `json:"qty" db:"qty"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Timestamp is the wire form of times in API responses and webhook payloads:
+// RFC 3339 in UTC, truncated to whole seconds.
+type Timestamp time.Time
+
+// NewTimestampPtr returns nil for a nil or zero time, so optional fields can
+// be omitted.
+func NewTimestampPtr(t *time.Time) *Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	ts := Timestamp(*t)
+	return &ts
+}
+
+func (t Timestamp) Time() time.Time {
+	return time.Time(t)
+}
+
+func (t Timestamp) String() string {
+	return time.Time(t).UTC().Format(time.RFC3339)
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(parsed)
+	return nil
+}
@@ -0,0 +1,36 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+)
+
+func TestTimestamp_JSON(t *testing.T) {
+	at := time.Date(2025, 1, 31, 17, 15, 0, 123456789, time.FixedZone("WIB", 7*3600))
+
+	data, err := json.Marshal(domain.Timestamp(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"2025-01-31T10:15:00Z"` {
+		t.Fatalf("got %s", data)
+	}
+
+	var back domain.Timestamp
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Time().Equal(at.Truncate(time.Second)) {
+		t.Errorf("round trip got %v, want %v", back.Time(), at.Truncate(time.Second))
+	}
+
+	if err := json.Unmarshal([]byte(`"31/01/2025"`), &back); err == nil {
+		t.Error("Expected an error for a non RFC 3339 time")
+	}
+	if domain.NewTimestampPtr(&time.Time{}) != nil {
+		t.Error("Expected nil for the zero time")
+	}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook events partners can subscribe to.
+const (
+	WebhookOrderCreated   = "order.created"
+	WebhookOrderUpdated   = "order.updated"
+	WebhookOrderCancelled = "order.cancelled"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookOrderCreated, WebhookOrderUpdated, WebhookOrderCancelled}
+
+// WebhookPayload is the body delivered to webhooks. EventID is unique per
+// order status change, so receivers can drop redelivered events.
+type WebhookPayload struct {
+	Event      string              `json:"event"`
+	EventID    int64               `json:"eventId"`
+	OccurredAt Timestamp           `json:"occurredAt"`
+	TenantID   string              `json:"tenantId"`
+	Order      WebhookOrderPayload `json:"order"`
+}
+
+type WebhookOrderPayload struct {
+	ID         int       `json:"id"`
+	ProductID  int       `json:"productId"`
+	CustomerID string    `json:"customerId"`
+	TotalPrice float64   `json:"totalPrice"`
+	Status     string    `json:"status"`
+	CreatedAt  Timestamp `json:"createdAt"`
+}
+
+// NewWebhookPayload encodes event for the change of o recorded by ev.
+func NewWebhookPayload(event string, o *Order, ev *OrderEvent) ([]byte, error) {
+	return json.Marshal(WebhookPayload{
+		Event:      event,
+		EventID:    ev.ID,
+		OccurredAt: Timestamp(ev.CreatedAt),
+		TenantID:   o.TenantID,
+		Order: WebhookOrderPayload{
+			ID:         o.ID,
+			ProductID:  o.ProductID,
+			CustomerID: o.CustomerID,
+			TotalPrice: o.TotalPrice,
+			Status:     o.Status,
+			CreatedAt:  Timestamp(o.CreatedAt),
+		},
+	})
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of a tenant that receives signed order events.
+// It is disabled after too many consecutive failed deliveries.
+type Webhook struct {
+	ID                  int        `db:"id"`
+	TenantID            string     `db:"tenant_id"`
+	URL                 string     `db:"url"`
+	Secret              string     `db:"secret"`
+	Events              []string   `db:"events"`
+	Active              bool       `db:"active"`
+	ConsecutiveFailures int        `db:"consecutive_failures"`
+	DisabledAt          *time.Time `db:"disabled_at"`
+	CreatedAt           time.Time  `db:"created_at"`
+	UpdatedAt           time.Time  `db:"updated_at"`
+}
+
+// Subscribes reports whether the webhook receives event.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for, or delivered to, a webhook.
+type WebhookDelivery struct {
+	ID             int64           `db:"id"`
+	TenantID       string          `db:"tenant_id"`
+	WebhookID      int             `db:"webhook_id"`
+	Event          string          `db:"event"`
+	Payload        json.RawMessage `db:"payload"`
+	Status         string          `db:"status"`
+	Attempts       int             `db:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `db:"last_attempt_at"`
+	ResponseStatus int             `db:"response_status"`
+	LastError      string          `db:"last_error"`
+	CreatedAt      time.Time       `db:"created_at"`
+}
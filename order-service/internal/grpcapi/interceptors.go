@@ -0,0 +1,211 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	orderv1 "github.com/dandiagusm/microservices-product-order/order-service/api/order/v1"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/ratelimit"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes lists the scope each order API method requires. Methods not
+// listed here (health, reflection) are public and not tenant scoped.
+var methodScopes = map[string]string{
+	orderv1.OrderService_CreateOrder_FullMethodName: auth.ScopeOrdersWrite,
+	orderv1.OrderService_CancelOrder_FullMethodName: auth.ScopeOrdersWrite,
+	orderv1.OrderService_GetOrder_FullMethodName:    auth.ScopeOrdersRead,
+	orderv1.OrderService_ListOrders_FullMethodName:  auth.ScopeOrdersRead,
+	orderv1.OrderService_WatchOrder_FullMethodName:  auth.ScopeOrdersRead,
+}
+
+// interceptors give gRPC calls what the HTTP middleware chain gives
+// requests: a request ID and logger, a server span, an access log and
+// metrics, then rate limiting, authentication and the tenant.
+type interceptors struct {
+	auth      *middleware.Auth
+	rateLimit *middleware.RateLimit
+	tenant    *middleware.Tenant
+}
+
+func (i *interceptors) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, finish := i.begin(ctx, info.FullMethod)
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	var resp interface{}
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	finish(err)
+	return resp, err
+}
+
+func (i *interceptors) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, finish := i.begin(ss.Context(), info.FullMethod)
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+	finish(err)
+	return err
+}
+
+// begin sets up the request ID, logger and span of a call and returns the
+// function that records its outcome.
+func (i *interceptors) begin(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := first(md, "x-request-id")
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, requestID)
+	ctx = logging.With(ctx, "request_id", requestID, "grpc_method", method)
+
+	ctx = tracing.ExtractGRPC(ctx, md)
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(method, "/"), trace.SpanKindServer,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", method),
+		attribute.String("request.id", requestID),
+	)
+
+	return ctx, func(err error) {
+		code := status.Code(err)
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+		var spanErr error
+		if isServerError(code) {
+			spanErr = err
+		}
+		tracing.End(span, spanErr)
+
+		elapsed := time.Since(start)
+		metrics.GRPCRequests.WithLabelValues(method, code.String()).Inc()
+		metrics.GRPCDuration.WithLabelValues(method, code.String()).Observe(elapsed.Seconds())
+		logging.FromContext(ctx).Info("gRPC request", "code", code.String(), "latency_ms", elapsed.Milliseconds())
+	}
+}
+
+// authorize rate limits and authenticates calls to the order API, checks
+// their scope and resolves their tenant. As over HTTP, the bucket of the
+// caller's IP address is charged before the token is verified and the
+// bucket of the client after it.
+func (i *interceptors) authorize(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var ip string
+	if i.rateLimit != nil {
+		var remoteAddr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remoteAddr = p.Addr.String()
+		}
+		ip = i.rateLimit.ClientIP(first(md, "x-forwarded-for"), remoteAddr)
+	}
+	if err := limited(ctx, i.rateLimit.AllowIP(ctx, ip)); err != nil {
+		return ctx, err
+	}
+
+	if i.auth != nil && i.auth.Verifier != nil {
+		token, ok := strings.CutPrefix(first(md, "authorization"), "Bearer ")
+		if !ok || token == "" {
+			return ctx, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		principal, err := i.auth.Verifier.Verify(ctx, token)
+		if err != nil {
+			logging.FromContext(ctx).Info("Token REJECTED", "error", err)
+			return ctx, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if !principal.HasScope(scope) {
+			return ctx, status.Error(codes.PermissionDenied, "missing scope "+scope)
+		}
+		ctx = auth.WithPrincipal(ctx, principal)
+		ctx = logging.With(ctx, "subject", principal.Subject)
+	}
+
+	var apiKey string
+	if i.rateLimit != nil && i.rateLimit.APIKeyHeader != "" {
+		apiKey = first(md, strings.ToLower(i.rateLimit.APIKeyHeader))
+	}
+	if err := limited(ctx, i.rateLimit.AllowClient(ctx, apiKey, ip)); err != nil {
+		return ctx, err
+	}
+
+	id, httpStatus, err := i.tenant.Resolve(ctx, first(md, strings.ToLower(i.tenant.Header)))
+	if err != nil {
+		if httpStatus == http.StatusForbidden {
+			return ctx, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return ctx, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx = tenant.WithTenant(ctx, id)
+	ctx = logging.With(ctx, "tenant", id)
+	return ctx, nil
+}
+
+// limited sends the ratelimit-* headers of res and answers
+// ResourceExhausted with retry-after once the bucket is empty. A nil res
+// lets the call through.
+func limited(ctx context.Context, res *ratelimit.Result) error {
+	if res == nil {
+		return nil
+	}
+	md := metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(res.Limit),
+		"ratelimit-remaining", strconv.Itoa(res.Remaining),
+		"ratelimit-reset", middleware.Seconds(res.Reset),
+	)
+	if res.Allowed {
+		_ = grpc.SetHeader(ctx, md)
+		return nil
+	}
+	md.Set("retry-after", middleware.Seconds(res.RetryAfter))
+	// the call ends without a response, so the metadata goes in the trailer
+	_ = grpc.SetTrailer(ctx, md)
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// serverStream replaces the context of a stream with the one built by the
+// interceptor.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	orderv1 "github.com/dandiagusm/microservices-product-order/order-service/api/order/v1"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/product"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Config struct {
+	Auth *middleware.Auth
+	// RateLimit throttles the order API with the same buckets as HTTP.
+	RateLimit  *middleware.RateLimit
+	Tenant     *middleware.Tenant
+	Reflection bool
+}
+
+// NewServer builds a gRPC server exposing s as order.v1.OrderService next
+// to the standard health service and, optionally, reflection. The returned
+// health server reports SERVING until its Shutdown is called.
+func NewServer(s *service.OrderService, cfg Config) (*grpc.Server, *health.Server) {
+	i := &interceptors{auth: cfg.Auth, rateLimit: cfg.RateLimit, tenant: cfg.Tenant}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+
+	orderv1.RegisterOrderServiceServer(srv, &OrderServer{Service: s})
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(orderv1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	if cfg.Reflection {
+		reflection.Register(srv)
+	}
+	return srv, healthSrv
+}
+
+// OrderServer implements order.v1.OrderService on top of the same
+// service.OrderService as the HTTP controllers, with the same ownership
+// rules: customers only see and cancel their own orders unless granted
+// orders:admin.
+type OrderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+	Service *service.OrderService
+}
+
+func (o *OrderServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.CreateOrderResponse, error) {
+	if req.GetProductId() > math.MaxInt32 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is out of range")
+	}
+	dto := domain.CreateOrderDTO{ProductID: int(req.GetProductId()), Quantity: int(req.GetQuantity())}
+	if errs := dto.Validate(); errs.HasErrors() {
+		return nil, status.Error(codes.InvalidArgument, errs.Error())
+	}
+
+	var customerID string
+	if p := auth.FromContext(ctx); p != nil {
+		customerID = p.Subject
+	}
+
+	order, err := o.Service.CreateOrder(ctx, customerID, dto.ProductID, dto.Quantity)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &orderv1.CreateOrderResponse{Order: toProto(order)}, nil
+}
+
+func (o *OrderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.GetOrderResponse, error) {
+	order, err := o.ownOrder(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &orderv1.GetOrderResponse{Order: toProto(order)}, nil
+}
+
+// ListOrders lists the orders of a product or a customer, or the caller's
+// own orders when the request names neither.
+func (o *OrderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	p := auth.FromContext(ctx)
+
+	var orders []*domain.Order
+	var err error
+	switch filter := req.GetFilter().(type) {
+	case *orderv1.ListOrdersRequest_ProductId:
+		productID, idErr := orderID(filter.ProductId)
+		if idErr != nil {
+			return nil, status.Error(codes.InvalidArgument, "product_id must be a positive integer")
+		}
+		if orders, err = o.Service.GetOrdersByProductID(ctx, productID); err != nil {
+			return nil, statusError(err)
+		}
+		// customers only see their own orders of the product
+		if p != nil && !p.HasScope(auth.ScopeOrdersAdmin) {
+			own := make([]*domain.Order, 0, len(orders))
+			for _, order := range orders {
+				if order.CustomerID == p.Subject {
+					own = append(own, order)
+				}
+			}
+			orders = own
+		}
+	case *orderv1.ListOrdersRequest_CustomerId:
+		if p != nil && !p.CanAccessCustomer(filter.CustomerId) {
+			return nil, status.Error(codes.PermissionDenied, "orders of another customer")
+		}
+		if orders, err = o.Service.GetOrdersByCustomerID(ctx, filter.CustomerId); err != nil {
+			return nil, statusError(err)
+		}
+	default:
+		if p == nil {
+			return nil, status.Error(codes.InvalidArgument, "product_id or customer_id is required")
+		}
+		if orders, err = o.Service.GetOrdersByCustomerID(ctx, p.Subject); err != nil {
+			return nil, statusError(err)
+		}
+	}
+
+	resp := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, toProto(order))
+	}
+	return resp, nil
+}
+
+func (o *OrderServer) CancelOrder(ctx context.Context, req *orderv1.CancelOrderRequest) (*orderv1.CancelOrderResponse, error) {
+	order, err := o.ownOrder(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if order, err = o.Service.CancelOrder(ctx, order.ID); err != nil {
+		return nil, statusError(err)
+	}
+	return &orderv1.CancelOrderResponse{Order: toProto(order)}, nil
+}
+
+func (o *OrderServer) WatchOrder(req *orderv1.WatchOrderRequest, stream orderv1.OrderService_WatchOrderServer) error {
+	ctx := stream.Context()
+	order, err := o.ownOrder(ctx, req.GetId())
+	if err != nil {
+		return err
+	}
+	err = o.Service.WatchOrder(ctx, order.ID, func(order *domain.Order) error {
+		return stream.Send(&orderv1.WatchOrderResponse{Order: toProto(order)})
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+// ownOrder loads an order the caller may access.
+func (o *OrderServer) ownOrder(ctx context.Context, id int64) (*domain.Order, error) {
+	orderID, err := orderID(id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "id must be a positive integer")
+	}
+	order, err := o.Service.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if p := auth.FromContext(ctx); p != nil && !p.CanAccessCustomer(order.CustomerID) {
+		return nil, status.Error(codes.PermissionDenied, "order of another customer")
+	}
+	return order, nil
+}
+
+var errInvalidID = errors.New("invalid id")
+
+func orderID(id int64) (int, error) {
+	if id <= 0 || id > math.MaxInt32 {
+		return 0, errInvalidID
+	}
+	return int(id), nil
+}
+
+// statusError maps service errors to gRPC status codes the way the HTTP
+// controller maps them to status codes.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound), errors.Is(err, product.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrOrderCancelled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, product.ErrUnavailable), errors.Is(err, service.ErrOverloaded):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toProto(o *domain.Order) *orderv1.Order {
+	return &orderv1.Order{
+		Id:         int64(o.ID),
+		TenantId:   o.TenantID,
+		ProductId:  int64(o.ProductID),
+		CustomerId: o.CustomerID,
+		TotalPrice: o.TotalPrice,
+		Status:     o.Status,
+		CreatedAt:  timestamppb.New(o.CreatedAt),
+	}
+}
@@ -0,0 +1,133 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	orderv1 "github.com/dandiagusm/microservices-product-order/order-service/api/order/v1"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/grpcapi"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/ratelimit"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the API without an order service behind it, which is enough
+// for calls the interceptors reject. rl may be nil.
+func dial(t *testing.T, rl *middleware.RateLimit) *grpc.ClientConn {
+	t.Helper()
+	verifier, err := auth.NewVerifier(auth.Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := grpcapi.NewServer(nil, grpcapi.Config{
+		Auth:      &middleware.Auth{Verifier: verifier},
+		RateLimit: rl,
+		Tenant:    &middleware.Tenant{Header: "X-Tenant-ID", Claim: "tenant_id", Default: "default"},
+	})
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func bearer(t *testing.T, claims jwt.MapClaims) context.Context {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestOrderServer_Authorization(t *testing.T) {
+	client := orderv1.NewOrderServiceClient(dial(t, nil))
+	exp := time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{name: "no token", ctx: context.Background(), want: codes.Unauthenticated},
+		{name: "missing scope", ctx: bearer(t, jwt.MapClaims{"sub": "alice", "scope": "orders:read", "exp": exp}), want: codes.PermissionDenied},
+		{name: "invalid tenant", ctx: bearer(t, jwt.MapClaims{"sub": "alice", "scope": "orders:write", "tenant_id": "a.b", "exp": exp}), want: codes.InvalidArgument},
+		{name: "tenant header without claim", ctx: metadata.AppendToOutgoingContext(
+			bearer(t, jwt.MapClaims{"sub": "alice", "scope": "orders:write", "exp": exp}), "x-tenant-id", "shop-a"), want: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateOrder(tt.ctx, &orderv1.CreateOrderRequest{ProductId: 1, Quantity: 1})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("Expected %s, got %s (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestNewServer_Health(t *testing.T) {
+	resp, err := healthpb.NewHealthClient(dial(t, nil)).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: orderv1.OrderService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		t.Fatalf("Expected health check without a token to succeed, got %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected SERVING, got %s", resp.GetStatus())
+	}
+}
+
+func TestOrderServer_RateLimit(t *testing.T) {
+	// a negligible rate so the buckets do not refill during the test
+	client := orderv1.NewOrderServiceClient(dial(t, &middleware.RateLimit{
+		Limiter:   ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 0.001, Burst: 1}, 100),
+		IPLimiter: ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 0.001, Burst: 3}, 100),
+	}))
+	exp := time.Now().Add(time.Minute).Unix()
+	call := func(ctx context.Context) (codes.Code, metadata.MD) {
+		var trailer metadata.MD
+		_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: 1}, grpc.Trailer(&trailer))
+		return status.Code(err), trailer
+	}
+
+	// the first call takes the only token of alice's bucket and is then
+	// turned away for its tenant, which the rate limit comes before
+	alice := bearer(t, jwt.MapClaims{"sub": "alice", "scope": "orders:read", "tenant_id": "a.b", "exp": exp})
+	if code, _ := call(alice); code != codes.InvalidArgument {
+		t.Fatalf("Expected the first call to pass the rate limit, got %s", code)
+	}
+	code, trailer := call(alice)
+	if code != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted once the client bucket is empty, got %s", code)
+	}
+	if len(trailer.Get("retry-after")) == 0 {
+		t.Error("Expected retry-after in the trailer")
+	}
+
+	// bad tokens are charged to the address before they are verified
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer not-a-token")
+	if code, _ := call(bad); code != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %s", code)
+	}
+	if code, _ := call(bad); code != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted once the address bucket is empty, got %s", code)
+	}
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+)
+
+// Check probes one dependency and returns nil when it is usable.
+type Check func(ctx context.Context) error
+
+type registration struct {
+	name     string
+	critical bool
+	check    Check
+}
+
+// Result is the outcome of a single dependency check.
+type Result struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report aggregates all dependency checks. Status is down when any critical
+// dependency is down, degraded when only non-critical ones are, up otherwise.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the registered readiness checks concurrently.
+type Checker struct {
+	timeout      time.Duration
+	checks       []registration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a dependency check. A failing critical check makes the
+// service not ready; a failing non-critical one only degrades it.
+func (c *Checker) Register(name string, critical bool, check Check) {
+	c.checks = append(c.checks, registration{name: name, critical: critical, check: check})
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing
+// traffic while the service drains.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, reg := range c.checks {
+		wg.Add(1)
+		go func(reg registration) {
+			defer wg.Done()
+			start := time.Now()
+			err := reg.check(ctx)
+			res := Result{Status: StatusUp, Critical: reg.critical, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Status = StatusDown
+				res.Error = err.Error()
+			}
+			mutex.Lock()
+			report.Checks[reg.name] = res
+			mutex.Unlock()
+		}(reg)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusDown {
+			continue
+		}
+		if res.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusDown
+		report.Checks["shutdown"] = Result{Status: StatusDown, Critical: true, Error: "service is shutting down"}
+	}
+	return report
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned by TTL when the key does not exist.
+var ErrNotFound = errors.New("cache: key not found")
+
+// NoExpiry is returned by TTL for keys stored without an expiration.
+const NoExpiry time.Duration = -1
+
+// Cache is a key/value store for JSON-encoded values. Get and GetMulti
+// report a miss as a nil value with a nil error.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+	SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Ping(ctx context.Context) error
+	Close() error
+
+	VersionedHash
+}
+
+// VersionedHash stores collections as hashes of individually addressable
+// fields. Each collection is published under a version number; a rebuild
+// writes a complete new version and switches to it atomically, so readers
+// see either the old or the new collection, never a partial one.
+//
+// Every field carries the revision of the entry it holds, and a field is
+// only ever replaced by a higher revision. Writers racing a rebuild from an
+// older snapshot therefore cannot be undone by it.
+type VersionedHash interface {
+	// HashGetAll returns the fields of the current version. ok is false
+	// when the collection is not cached.
+	HashGetAll(ctx context.Context, key string) (fields map[string][]byte, ok bool, err error)
+	// HashSet writes one field into the cached collection unless it already
+	// holds the same or a higher revision. While the collection is not
+	// cached the field is set aside and merged into the next HashReplace.
+	HashSet(ctx context.Context, key, field string, value HashField, ttl time.Duration) error
+	// HashReplace publishes fields as a new version of the collection. For
+	// each field the highest revision among fields, the current version and
+	// the writes set aside by HashSet wins; fields missing from fields are
+	// carried over.
+	HashReplace(ctx context.Context, key string, fields map[string]HashField, ttl time.Duration) error
+	// HashDelete drops a collection, so the next read rebuilds it.
+	HashDelete(ctx context.Context, key string) error
+}
+
+// HashField is a value stored in a VersionedHash with the revision of the
+// entry it holds.
+type HashField struct {
+	Revision int64
+	Value    interface{}
+}
+
+var (
+	_ Cache = (*RedisClient)(nil)
+	_ Cache = (*MemoryCache)(nil)
+	_ Cache = NoopCache{}
+)
+
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+	DriverNone   = "none"
+)
+
+type Config struct {
+	Driver         string
+	Redis          RedisConfig
+	MemoryMaxItems int
+}
+
+// New builds the Cache implementation selected by cfg.Driver.
+func New(cfg Config) (Cache, error) {
+	switch cfg.Driver {
+	case "", DriverRedis:
+		return NewRedisClient(cfg.Redis)
+	case DriverMemory:
+		return NewMemoryCache(cfg.MemoryMaxItems), nil
+	case DriverNone:
+		return NewNoopCache(), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryMaxItems bounds the in-memory cache when no size is configured.
+const DefaultMemoryMaxItems = 10000
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+// MemoryCache is a bounded, process-local LRU cache. It is meant for local
+// runs and tests where Redis is not available.
+type MemoryCache struct {
+	maxItems int
+
+	mutex sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+
+	hashMutex sync.Mutex
+	hashes    map[string]*memoryHash
+	pending   map[string]*memoryHash
+}
+
+func NewMemoryCache(maxItems int) *MemoryCache {
+	if maxItems <= 0 {
+		maxItems = DefaultMemoryMaxItems
+	}
+	return &MemoryCache{
+		maxItems: maxItems,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+		hashes:   make(map[string]*memoryHash),
+		pending:  make(map[string]*memoryHash),
+	}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.get(key), nil
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.set(key, data, ttl)
+	return nil
+}
+
+func (m *MemoryCache) Delete(_ context.Context, keys ...string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCache) GetMulti(_ context.Context, keys []string) (map[string][]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if v := m.get(key); v != nil {
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+func (m *MemoryCache) SetMulti(_ context.Context, items map[string]interface{}, ttl time.Duration) error {
+	encoded := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", key, err)
+		}
+		encoded[key] = data
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key, data := range encoded {
+		m.set(key, data, ttl)
+	}
+	return nil
+}
+
+func (m *MemoryCache) TTL(_ context.Context, key string) (time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	item := el.Value.(*memoryItem)
+	now := m.now()
+	if item.expired(now) {
+		m.remove(el)
+		return 0, ErrNotFound
+	}
+	if item.expiresAt.IsZero() {
+		return NoExpiry, nil
+	}
+	return item.expiresAt.Sub(now), nil
+}
+
+func (m *MemoryCache) Ping(context.Context) error { return nil }
+
+func (m *MemoryCache) Close() error { return nil }
+
+// Len returns the number of stored entries, including expired ones not yet evicted.
+func (m *MemoryCache) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.ll.Len()
+}
+
+func (m *MemoryCache) get(key string) []byte {
+	el, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	item := el.Value.(*memoryItem)
+	if item.expired(m.now()) {
+		m.remove(el)
+		return nil
+	}
+	m.ll.MoveToFront(el)
+	return item.value
+}
+
+func (m *MemoryCache) set(key string, data []byte, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+	if el, ok := m.items[key]; ok {
+		item := el.Value.(*memoryItem)
+		item.value = data
+		item.expiresAt = expiresAt
+		m.ll.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.ll.PushFront(&memoryItem{key: key, value: data, expiresAt: expiresAt})
+	for m.ll.Len() > m.maxItems {
+		m.remove(m.ll.Back())
+	}
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*memoryItem).key)
+}
+
+type memoryHash struct {
+	fields    map[string]memoryHashField
+	expiresAt time.Time
+}
+
+type memoryHashField struct {
+	revision int64
+	data     []byte
+}
+
+// put stores f unless the hash already holds the same or a higher revision.
+func (h *memoryHash) put(field string, f memoryHashField) {
+	if cur, ok := h.fields[field]; ok && cur.revision >= f.revision {
+		return
+	}
+	h.fields[field] = f
+}
+
+func (m *MemoryCache) HashGetAll(_ context.Context, key string) (map[string][]byte, bool, error) {
+	m.hashMutex.Lock()
+	defer m.hashMutex.Unlock()
+	h := m.liveHash(key)
+	if h == nil {
+		return nil, false, nil
+	}
+	fields := make(map[string][]byte, len(h.fields))
+	for k, v := range h.fields {
+		fields[k] = v.data
+	}
+	return fields, true, nil
+}
+
+func (m *MemoryCache) HashSet(_ context.Context, key, field string, value HashField, ttl time.Duration) error {
+	data, err := json.Marshal(value.Value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	m.hashMutex.Lock()
+	defer m.hashMutex.Unlock()
+	h := m.liveHash(key)
+	if h == nil {
+		h = m.livePending(key)
+		if h == nil {
+			if len(m.pending) >= m.maxItems {
+				for k := range m.pending {
+					delete(m.pending, k)
+					break
+				}
+			}
+			h = &memoryHash{fields: make(map[string]memoryHashField)}
+			m.pending[key] = h
+		}
+	}
+	h.put(field, memoryHashField{revision: value.Revision, data: data})
+	if ttl > 0 {
+		h.expiresAt = m.now().Add(ttl)
+	}
+	return nil
+}
+
+func (m *MemoryCache) HashReplace(_ context.Context, key string, fields map[string]HashField, ttl time.Duration) error {
+	h := &memoryHash{fields: make(map[string]memoryHashField, len(fields))}
+	for field, value := range fields {
+		data, err := json.Marshal(value.Value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", field, err)
+		}
+		h.put(field, memoryHashField{revision: value.Revision, data: data})
+	}
+	if ttl > 0 {
+		h.expiresAt = m.now().Add(ttl)
+	}
+
+	m.hashMutex.Lock()
+	defer m.hashMutex.Unlock()
+	for _, src := range []*memoryHash{m.liveHash(key), m.livePending(key)} {
+		if src == nil {
+			continue
+		}
+		for k, v := range src.fields {
+			h.put(k, v)
+		}
+	}
+	delete(m.pending, key)
+	if _, exists := m.hashes[key]; !exists && len(m.hashes) >= m.maxItems {
+		for k := range m.hashes {
+			delete(m.hashes, k)
+			break
+		}
+	}
+	m.hashes[key] = h
+	return nil
+}
+
+func (m *MemoryCache) HashDelete(_ context.Context, key string) error {
+	m.hashMutex.Lock()
+	defer m.hashMutex.Unlock()
+	delete(m.hashes, key)
+	return nil
+}
+
+func (m *MemoryCache) livePending(key string) *memoryHash {
+	h, ok := m.pending[key]
+	if !ok {
+		return nil
+	}
+	if !h.expiresAt.IsZero() && !m.now().Before(h.expiresAt) {
+		delete(m.pending, key)
+		return nil
+	}
+	return h
+}
+
+func (m *MemoryCache) liveHash(key string) *memoryHash {
+	h, ok := m.hashes[key]
+	if !ok {
+		return nil
+	}
+	if !h.expiresAt.IsZero() && !m.now().Before(h.expiresAt) {
+		delete(m.hashes, key)
+		return nil
+	}
+	return h
+}
@@ -0,0 +1,59 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/cache"
+)
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemoryCache(2)
+
+	_ = c.Set(ctx, "a", 1, 0)
+	_ = c.Set(ctx, "b", 2, 0)
+	if v, _ := c.Get(ctx, "a"); string(v) != "1" {
+		t.Fatalf("Expected a=1, got %q", v)
+	}
+	_ = c.Set(ctx, "c", 3, 0)
+
+	if v, _ := c.Get(ctx, "b"); v != nil {
+		t.Errorf("Expected b to be evicted, got %q", v)
+	}
+	got, _ := c.GetMulti(ctx, []string{"a", "b", "c"})
+	if len(got) != 2 || string(got["a"]) != "1" || string(got["c"]) != "3" {
+		t.Errorf("Unexpected GetMulti result: %v", got)
+	}
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemoryCache(10)
+
+	_ = c.Set(ctx, "short", "x", 20*time.Millisecond)
+	_ = c.Set(ctx, "forever", "y", 0)
+
+	if ttl, err := c.TTL(ctx, "forever"); err != nil || ttl != cache.NoExpiry {
+		t.Errorf("Expected NoExpiry, got %v (%v)", ttl, err)
+	}
+	if ttl, err := c.TTL(ctx, "short"); err != nil || ttl <= 0 {
+		t.Errorf("Expected positive TTL, got %v (%v)", ttl, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if v, _ := c.Get(ctx, "short"); v != nil {
+		t.Errorf("Expected expired key to miss, got %q", v)
+	}
+	if _, err := c.TTL(ctx, "short"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	_ = c.Delete(ctx, "forever")
+	if v, _ := c.Get(ctx, "forever"); v != nil {
+		t.Errorf("Expected deleted key to miss, got %q", v)
+	}
+}
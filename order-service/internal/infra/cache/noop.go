@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NoopCache stores nothing; every read is a miss.
+type NoopCache struct{}
+
+func NewNoopCache() NoopCache { return NoopCache{} }
+
+func (NoopCache) Get(context.Context, string) ([]byte, error) { return nil, nil }
+
+func (NoopCache) Set(context.Context, string, interface{}, time.Duration) error { return nil }
+
+func (NoopCache) Delete(context.Context, ...string) error { return nil }
+
+func (NoopCache) GetMulti(context.Context, []string) (map[string][]byte, error) {
+	return map[string][]byte{}, nil
+}
+
+func (NoopCache) SetMulti(context.Context, map[string]interface{}, time.Duration) error { return nil }
+
+func (NoopCache) TTL(context.Context, string) (time.Duration, error) { return 0, ErrNotFound }
+
+func (NoopCache) Ping(context.Context) error { return nil }
+
+func (NoopCache) Close() error { return nil }
+
+func (NoopCache) HashGetAll(context.Context, string) (map[string][]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (NoopCache) HashSet(context.Context, string, string, HashField, time.Duration) error {
+	return nil
+}
+
+func (NoopCache) HashReplace(context.Context, string, map[string]HashField, time.Duration) error {
+	return nil
+}
+
+func (NoopCache) HashDelete(context.Context, string) error { return nil }
@@ -3,52 +3,131 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisClient struct {
-	client *redis.Client
-	ctx    context.Context
+	client redis.UniversalClient
 }
 
-// NewRedisClient creates a Redis client and verifies the connection.
-func NewRedisClient(host, port string) (*RedisClient, error) {
-	if host == "" || port == "" {
-		return nil, fmt.Errorf("REDIS_HOST or REDIS_PORT is not defined")
+// NewRedisClient creates a standalone, Sentinel or Cluster Redis client
+// and verifies the connection.
+func NewRedisClient(cfg RedisConfig) (*RedisClient, error) {
+	rdb, err := newUniversalClient(cfg)
+	if err != nil {
+		return nil, err
 	}
+	rdb.AddHook(tracing.RedisHook{})
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%s", host, port),
-	})
-
-	ctx := context.Background()
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		_ = rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisClient{client: rdb, ctx: ctx}, nil
+	return &RedisClient{client: rdb}, nil
 }
 
-// Set stores a value in Redis with TTL in seconds.
-func (r *RedisClient) Set(key string, value interface{}, ttlSeconds int) error {
+// Set stores a value in Redis with the given TTL. A zero TTL means no expiry.
+func (r *RedisClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
-	return r.client.Set(r.ctx, key, data, time.Duration(ttlSeconds)*time.Second).Err()
+	return r.client.Set(ctx, key, data, ttl).Err()
 }
 
 // Get retrieves a value from Redis.
-func (r *RedisClient) Get(key string) ([]byte, error) {
-	data, err := r.client.Get(r.ctx, key).Bytes()
+func (r *RedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
-		if err == redis.Nil {
+		if errors.Is(err, redis.Nil) {
 			return nil, nil // key not found
 		}
 		return nil, err
 	}
 	return data, nil
 }
+
+// Delete removes keys from Redis.
+func (r *RedisClient) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// GetMulti retrieves several keys in one pipelined round trip. Missing keys
+// are absent from the result. A pipeline rather than MGET keeps this working
+// in Cluster mode, where keys may live in different slots.
+func (r *RedisClient) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	for i, cmd := range cmds {
+		if data, err := cmd.Bytes(); err == nil {
+			result[keys[i]] = data
+		}
+	}
+	return result, nil
+}
+
+// SetMulti stores several values with the same TTL in one pipeline.
+func (r *RedisClient) SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for key, value := range items {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", key, err)
+		}
+		pipe.Set(ctx, key, data, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// TTL returns the remaining time to live of a key.
+func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// go-redis reports the PTTL sentinels -2 (missing) and -1 (no expiry) unscaled.
+	switch ttl {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return NoExpiry, nil
+	}
+	return ttl, nil
+}
+
+func (r *RedisClient) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *RedisClient) Close() error {
+	return r.client.Close()
+}
+
+// Client exposes the underlying client to components that share the
+// connection, such as the rate limiter.
+func (r *RedisClient) Client() redis.UniversalClient {
+	return r.client
+}
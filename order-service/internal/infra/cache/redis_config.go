@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// RedisConfig describes how to reach Redis. Addrs holds the server address
+// in standalone mode, the sentinel addresses in sentinel mode and the seed
+// nodes in cluster mode.
+type RedisConfig struct {
+	Mode       string
+	Addrs      []string
+	MasterName string
+	DB         int
+
+	Username         string
+	Password         string
+	SentinelUsername string
+	SentinelPassword string
+
+	TLSEnabled            bool
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+func (c RedisConfig) validate() error {
+	if len(c.Addrs) == 0 {
+		return fmt.Errorf("redis: at least one address is required")
+	}
+	switch c.Mode {
+	case "", RedisModeStandalone:
+		if len(c.Addrs) > 1 {
+			return fmt.Errorf("redis: standalone mode takes a single address, got %d", len(c.Addrs))
+		}
+	case RedisModeSentinel:
+		if c.MasterName == "" {
+			return fmt.Errorf("redis: sentinel mode requires a master name")
+		}
+	case RedisModeCluster:
+		if c.DB != 0 {
+			return fmt.Errorf("redis: cluster mode does not support selecting a DB")
+		}
+	default:
+		return fmt.Errorf("redis: unknown mode %q", c.Mode)
+	}
+	return nil
+}
+
+func (c RedisConfig) tlsConfig() (*tls.Config, error) {
+	if !c.TLSEnabled {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates found in %s", c.TLSCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// newUniversalClient builds the go-redis client matching the configured topology.
+func newUniversalClient(c RedisConfig) (redis.UniversalClient, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Mode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       c.MasterName,
+			SentinelAddrs:    c.Addrs,
+			SentinelUsername: c.SentinelUsername,
+			SentinelPassword: c.SentinelPassword,
+			Username:         c.Username,
+			Password:         c.Password,
+			DB:               c.DB,
+			TLSConfig:        tlsCfg,
+			PoolSize:         c.PoolSize,
+			MinIdleConns:     c.MinIdleConns,
+			DialTimeout:      c.DialTimeout,
+			ReadTimeout:      c.ReadTimeout,
+			WriteTimeout:     c.WriteTimeout,
+			PoolTimeout:      c.PoolTimeout,
+		}), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        c.Addrs,
+			Username:     c.Username,
+			Password:     c.Password,
+			TLSConfig:    tlsCfg,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			PoolTimeout:  c.PoolTimeout,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:         c.Addrs[0],
+			Username:     c.Username,
+			Password:     c.Password,
+			DB:           c.DB,
+			TLSConfig:    tlsCfg,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			PoolTimeout:  c.PoolTimeout,
+		}), nil
+	}
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Versioned hashes are laid out as
+//
+//	<key>:version  -> current version number n
+//	<key>:v<n>     -> hash of field -> "<revision>:<JSON value>", plus a marker field
+//	<key>:pending  -> fields written while no version was cached
+//
+// Callers should put a hash tag in key (e.g. "orders:product:{1}") so that
+// all of these land in the same Cluster slot. The scripts get every key
+// they touch through KEYS; since v<n> depends on the version, callers read
+// the version first and the scripts return hashVersionMoved when it has
+// changed in between.
+
+const (
+	hashMarkerField  = "_"
+	hashVersionMoved = -1
+	hashAttempts     = 5
+)
+
+// hashFieldLua compares revisions and keeps the higher one.
+const hashFieldLua = `
+local function rev(s)
+	return tonumber(string.match(s, '^(%d+):')) or 0
+end
+local function put(h, f, val)
+	local cur = redis.call('HGET', h, f)
+	if cur and rev(cur) >= rev(val) then return 0 end
+	redis.call('HSET', h, f, val)
+	return 1
+end
+`
+
+// KEYS: version, v<n>; ARGV: n
+var hashGetAllScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1]) or ''
+if v ~= ARGV[1] then return ` + strconv.Itoa(hashVersionMoved) + ` end
+if v == '' then return false end
+return redis.call('HGETALL', KEYS[2])
+`)
+
+// KEYS: version, v<n>, pending; ARGV: n, field, "<revision>:<value>", ttl
+var hashSetScript = redis.NewScript(hashFieldLua + `
+local v = redis.call('GET', KEYS[1]) or ''
+if v ~= ARGV[1] then return ` + strconv.Itoa(hashVersionMoved) + ` end
+local h = KEYS[2]
+if v == '' or redis.call('EXISTS', h) == 0 then h = KEYS[3] end
+local written = put(h, ARGV[2], ARGV[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('PEXPIRE', h, ARGV[4])
+	if h == KEYS[2] then redis.call('PEXPIRE', KEYS[1], ARGV[4]) end
+end
+return written
+`)
+
+// KEYS: version, v<n>, v<n+1>, pending; ARGV: n, ttl, then field, "<revision>:<value>" pairs
+var hashReplaceScript = redis.NewScript(hashFieldLua + `
+local v = redis.call('GET', KEYS[1]) or ''
+if v ~= ARGV[1] then return ` + strconv.Itoa(hashVersionMoved) + ` end
+local h = KEYS[3]
+redis.call('DEL', h)
+redis.call('HSET', h, '` + hashMarkerField + `', '')
+for i = 3, #ARGV, 2 do
+	put(h, ARGV[i], ARGV[i + 1])
+end
+for _, src in ipairs({KEYS[2], KEYS[4]}) do
+	local entries = redis.call('HGETALL', src)
+	for i = 1, #entries, 2 do
+		if entries[i] ~= '` + hashMarkerField + `' then put(h, entries[i], entries[i + 1]) end
+	end
+end
+redis.call('DEL', KEYS[2], KEYS[4])
+local n = (tonumber(v) or 0) + 1
+redis.call('SET', KEYS[1], n)
+if tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', h, ARGV[2])
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n
+`)
+
+// KEYS: version, v<n>; ARGV: n
+var hashDeleteScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1]) or ''
+if v ~= ARGV[1] then return ` + strconv.Itoa(hashVersionMoved) + ` end
+return redis.call('DEL', KEYS[1], KEYS[2])
+`)
+
+func hashVersionKey(key string) string { return key + ":version" }
+func hashPendingKey(key string) string { return key + ":pending" }
+
+func hashDataKey(key, version string) string {
+	if version == "" {
+		version = "0"
+	}
+	return key + ":v" + version
+}
+
+// hashVersion returns the current version of key, or "" when there is none.
+func (r *RedisClient) hashVersion(ctx context.Context, key string) (string, error) {
+	v, err := r.client.Get(ctx, hashVersionKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return v, err
+}
+
+// runHashScript runs script against the current version of key, retrying
+// when a concurrent rebuild moves the version in between.
+func (r *RedisClient) runHashScript(ctx context.Context, key string, run func(version string) (interface{}, error)) (interface{}, error) {
+	for i := 0; i < hashAttempts; i++ {
+		version, err := r.hashVersion(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		res, err := run(version)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := res.(int64); ok && n == hashVersionMoved {
+			continue
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("versioned hash %s kept moving", key)
+}
+
+func encodeHashField(value HashField) (string, error) {
+	data, err := json.Marshal(value.Value)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(value.Revision, 10) + ":" + string(data), nil
+}
+
+// decodeHashField strips the revision. Values written before revisions
+// were introduced have none and are returned as they are.
+func decodeHashField(s string) []byte {
+	if i := strings.IndexByte(s, ':'); i > 0 {
+		if _, err := strconv.ParseInt(s[:i], 10, 64); err == nil {
+			return []byte(s[i+1:])
+		}
+	}
+	return []byte(s)
+}
+
+func (r *RedisClient) HashGetAll(ctx context.Context, key string) (map[string][]byte, bool, error) {
+	res, err := r.runHashScript(ctx, key, func(version string) (interface{}, error) {
+		keys := []string{hashVersionKey(key), hashDataKey(key, version)}
+		res, err := hashGetAllScript.Run(ctx, r.client, keys, version).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return res, err
+	})
+	if err != nil || res == nil {
+		return nil, false, err
+	}
+	entries, _ := res.([]interface{})
+	if len(entries) == 0 {
+		// version pointer outlived its hash
+		return nil, false, nil
+	}
+
+	fields := make(map[string][]byte, len(entries)/2)
+	for i := 0; i+1 < len(entries); i += 2 {
+		field, _ := entries[i].(string)
+		value, _ := entries[i+1].(string)
+		if field == hashMarkerField {
+			continue
+		}
+		fields[field] = decodeHashField(value)
+	}
+	return fields, true, nil
+}
+
+func (r *RedisClient) HashSet(ctx context.Context, key, field string, value HashField, ttl time.Duration) error {
+	data, err := encodeHashField(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	_, err = r.runHashScript(ctx, key, func(version string) (interface{}, error) {
+		keys := []string{hashVersionKey(key), hashDataKey(key, version), hashPendingKey(key)}
+		return hashSetScript.Run(ctx, r.client, keys, version, field, data, ttl.Milliseconds()).Result()
+	})
+	return err
+}
+
+func (r *RedisClient) HashReplace(ctx context.Context, key string, fields map[string]HashField, ttl time.Duration) error {
+	args := make([]interface{}, 0, 2+2*len(fields))
+	args = append(args, "", ttl.Milliseconds())
+	for field, value := range fields {
+		data, err := encodeHashField(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", field, err)
+		}
+		args = append(args, field, data)
+	}
+	_, err := r.runHashScript(ctx, key, func(version string) (interface{}, error) {
+		next := "1"
+		if version != "" {
+			n, err := strconv.Atoi(version)
+			if err != nil {
+				return nil, fmt.Errorf("bad version %q of %s: %w", version, key, err)
+			}
+			next = strconv.Itoa(n + 1)
+		}
+		keys := []string{hashVersionKey(key), hashDataKey(key, version), hashDataKey(key, next), hashPendingKey(key)}
+		args[0] = version
+		return hashReplaceScript.Run(ctx, r.client, keys, args...).Result()
+	})
+	return err
+}
+
+// HashDelete keeps the writes set aside by HashSet: they hold the newest
+// known revisions, which a rebuild from an older snapshot still needs.
+func (r *RedisClient) HashDelete(ctx context.Context, key string) error {
+	_, err := r.runHashScript(ctx, key, func(version string) (interface{}, error) {
+		keys := []string{hashVersionKey(key), hashDataKey(key, version)}
+		return hashDeleteScript.Run(ctx, r.client, keys, version).Result()
+	})
+	return err
+}
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/cache"
+)
+
+func versionedHashes(t *testing.T) map[string]cache.VersionedHash {
+	mr := miniredis.RunT(t)
+	rc, err := cache.NewRedisClient(cache.RedisConfig{Addrs: []string{mr.Addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	return map[string]cache.VersionedHash{
+		"memory": cache.NewMemoryCache(10),
+		"redis":  rc,
+	}
+}
+
+func field(rev int64, v string) cache.HashField {
+	return cache.HashField{Revision: rev, Value: v}
+}
+
+func TestVersionedHash(t *testing.T) {
+	for name, c := range versionedHashes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// writes to an uncached collection are not visible
+			_ = c.HashSet(ctx, "orders", "1", field(1, "a"), time.Minute)
+			if _, ok, _ := c.HashGetAll(ctx, "orders"); ok {
+				t.Fatal("Expected collection to be uncached")
+			}
+
+			_ = c.HashReplace(ctx, "orders", map[string]cache.HashField{}, time.Minute)
+			fields, ok, _ := c.HashGetAll(ctx, "orders")
+			if !ok || len(fields) != 1 || string(fields["1"]) != `"a"` {
+				t.Fatalf("Expected the write set aside before the first fill, got %v (ok=%v)", fields, ok)
+			}
+
+			_ = c.HashSet(ctx, "orders", "3", field(1, "c"), time.Minute)
+			_ = c.HashReplace(ctx, "orders", map[string]cache.HashField{"2": field(1, "b")}, time.Minute)
+			fields, _, _ = c.HashGetAll(ctx, "orders")
+			if len(fields) != 3 || string(fields["2"]) != `"b"` || string(fields["3"]) != `"c"` {
+				t.Errorf("Expected entries written before the rebuild to be kept, got %v", fields)
+			}
+
+			if err := c.HashDelete(ctx, "orders"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := c.HashGetAll(ctx, "orders"); ok {
+				t.Error("Expected HashDelete to drop the collection")
+			}
+		})
+	}
+}
+
+func TestVersionedHash_KeepsNewestRevision(t *testing.T) {
+	for name, c := range versionedHashes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// an update lands between a reader's snapshot and its rebuild
+			_ = c.HashReplace(ctx, "orders", map[string]cache.HashField{"1": field(1, "waiting")}, time.Minute)
+			_ = c.HashSet(ctx, "orders", "1", field(2, "done"), time.Minute)
+			_ = c.HashReplace(ctx, "orders", map[string]cache.HashField{"1": field(1, "waiting")}, time.Minute)
+
+			fields, _, _ := c.HashGetAll(ctx, "orders")
+			if string(fields["1"]) != `"done"` {
+				t.Errorf("Expected the newer revision to survive the rebuild, got %s", fields["1"])
+			}
+
+			_ = c.HashSet(ctx, "orders", "1", field(1, "waiting"), time.Minute)
+			fields, _, _ = c.HashGetAll(ctx, "orders")
+			if string(fields["1"]) != `"done"` {
+				t.Errorf("Expected an older revision to be ignored, got %s", fields["1"])
+			}
+
+			// the same race on the first fill
+			_ = c.HashSet(ctx, "customer", "1", field(2, "done"), time.Minute)
+			_ = c.HashReplace(ctx, "customer", map[string]cache.HashField{"1": field(1, "waiting")}, time.Minute)
+			fields, _, _ = c.HashGetAll(ctx, "customer")
+			if string(fields["1"]) != `"done"` {
+				t.Errorf("Expected the write set aside to beat the older snapshot, got %s", fields["1"])
+			}
+		})
+	}
+}
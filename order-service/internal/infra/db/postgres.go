@@ -1,38 +1,97 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
-	_ "github.com/lib/pq"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PostgresDB struct {
 	Conn *sql.DB
+
+	// dispatcher connects as Config.DispatcherUser, which the webhook
+	// tables let see every tenant; nil when that is not configured.
+	dispatcher     *sql.DB
+	dispatcherRole string
+}
+
+type Config struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// DispatcherUser and DispatcherPassword are the role the webhook
+	// dispatcher connects as. Leave them empty when this replica does not
+	// dispatch webhooks.
+	DispatcherUser     string
+	DispatcherPassword string
 }
 
-func NewPostgresDB(host, user, password, dbname string, port int) (*PostgresDB, error) {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+func NewPostgresDB(cfg Config) (*PostgresDB, error) {
+	db, err := open(cfg, cfg.User, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	pg := &PostgresDB{Conn: db, dispatcherRole: cfg.DispatcherUser}
+
+	if cfg.DispatcherUser != "" {
+		pg.dispatcher, err = open(cfg, cfg.DispatcherUser, cfg.DispatcherPassword)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("webhook dispatcher role: %w", err)
+		}
+	}
+
+	pg.AutoMigrate()
+	return pg, nil
+}
+
+func open(cfg Config, user, password string) (*sql.DB, error) {
+	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, user, password, cfg.Name, cfg.SSLMode)
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, err
 	}
 
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(50)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	if err = db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
+	return db, nil
+}
 
-	pg := &PostgresDB{Conn: db}
-	pg.AutoMigrate()
-	return pg, nil
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	return p.Conn.PingContext(ctx)
+}
+
+func (p *PostgresDB) Close() error {
+	if p.dispatcher != nil {
+		p.dispatcher.Close()
+	}
+	return p.Conn.Close()
 }
 
 func (p *PostgresDB) AutoMigrate() {
@@ -45,40 +104,559 @@ func (p *PostgresDB) AutoMigrate() {
 		created_at TIMESTAMP NOT NULL DEFAULT now()
 	)`
 	if _, err := p.Conn.Exec(query); err != nil {
-		log.Fatalf("Failed to auto-migrate orders table: %v", err)
+		slog.Error("FAILED to auto-migrate orders table", "error", err)
+		os.Exit(1)
+	}
+
+	query = `
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS orders_customer_id_idx ON orders (customer_id)`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to add customer_id to orders table", "error", err)
+		os.Exit(1)
+	}
+
+	// Row-level security backs up the tenant_id filter of every query.
+	// Superusers and roles with BYPASSRLS are not subject to it.
+	query = `
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '` + DefaultTenant + `';
+	CREATE INDEX IF NOT EXISTS orders_tenant_product_idx ON orders (tenant_id, product_id);
+	CREATE INDEX IF NOT EXISTS orders_tenant_customer_idx ON orders (tenant_id, customer_id);
+	ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE orders FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS tenant_isolation ON orders;
+	CREATE POLICY tenant_isolation ON orders
+		USING (tenant_id = current_setting('app.tenant_id', true))
+		WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to add tenant isolation to orders table", "error", err)
+		os.Exit(1)
+	}
+
+	query = `ALTER TABLE orders ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 1`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to add revision to orders table", "error", err)
+		os.Exit(1)
+	}
+
+	query = `
+	CREATE TABLE IF NOT EXISTS order_events (
+		id BIGSERIAL PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		order_id INT NOT NULL,
+		customer_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS order_events_order_idx ON order_events (tenant_id, order_id, id);
+	CREATE INDEX IF NOT EXISTS order_events_customer_idx ON order_events (tenant_id, customer_id, id);
+	ALTER TABLE order_events ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE order_events FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS tenant_isolation ON order_events;
+	CREATE POLICY tenant_isolation ON order_events
+		USING (tenant_id = current_setting('app.tenant_id', true))
+		WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to auto-migrate order_events table", "error", err)
+		os.Exit(1)
+	}
+
+	// IDs are handed out at insert time, so they may commit out of order.
+	// seq is taken from a per-tenant counter row that stays locked until
+	// commit, so it orders the events of a tenant by commit instead. Older
+	// events keep their ID as seq, and RLS is lifted while backfilling.
+	query = `
+	ALTER TABLE order_events ADD COLUMN IF NOT EXISTS seq BIGINT;
+	CREATE TABLE IF NOT EXISTS order_event_sequences (
+		tenant_id TEXT PRIMARY KEY,
+		last_seq BIGINT NOT NULL
+	);
+	ALTER TABLE order_events NO FORCE ROW LEVEL SECURITY;
+	ALTER TABLE order_event_sequences NO FORCE ROW LEVEL SECURITY;
+	UPDATE order_events SET seq = id WHERE seq IS NULL;
+	INSERT INTO order_event_sequences (tenant_id, last_seq)
+		SELECT tenant_id, max(seq) FROM order_events GROUP BY tenant_id
+		ON CONFLICT (tenant_id) DO NOTHING;
+	ALTER TABLE order_events FORCE ROW LEVEL SECURITY;
+	ALTER TABLE order_events ALTER COLUMN seq SET NOT NULL;
+	CREATE INDEX IF NOT EXISTS order_events_order_seq_idx ON order_events (tenant_id, order_id, seq);
+	CREATE INDEX IF NOT EXISTS order_events_customer_seq_idx ON order_events (tenant_id, customer_id, seq);
+	DROP INDEX IF EXISTS order_events_order_idx;
+	DROP INDEX IF EXISTS order_events_customer_idx;
+	ALTER TABLE order_event_sequences ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE order_event_sequences FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS tenant_isolation ON order_event_sequences;
+	CREATE POLICY tenant_isolation ON order_event_sequences
+		USING (tenant_id = current_setting('app.tenant_id', true))
+		WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to add seq to order_events table", "error", err)
+		os.Exit(1)
+	}
+
+	query = `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id SERIAL PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT true,
+		consecutive_failures INT NOT NULL DEFAULT 0,
+		disabled_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT now(),
+		updated_at TIMESTAMP NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS webhooks_tenant_idx ON webhooks (tenant_id, id);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		webhook_id INT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+		last_attempt_at TIMESTAMP,
+		response_status INT NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (tenant_id, webhook_id, id);
+	ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS tenant_isolation ON webhooks;
+	CREATE POLICY tenant_isolation ON webhooks
+		USING (tenant_id = current_setting('app.tenant_id', true))
+		WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+	ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+	CREATE POLICY tenant_isolation ON webhook_deliveries
+		USING (tenant_id = current_setting('app.tenant_id', true))
+		WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to auto-migrate webhook tables", "error", err)
+		os.Exit(1)
+	}
+
+	// The webhook dispatcher delivers for every tenant. It connects as a
+	// role of its own, which a policy lets past the tenant check; nothing
+	// the app role can set in a session grants the same.
+	if p.dispatcherRole != "" {
+		query = fmt.Sprintf(`
+		GRANT SELECT, UPDATE ON webhooks, webhook_deliveries TO %[1]s;
+		DROP POLICY IF EXISTS webhook_dispatcher ON webhooks;
+		CREATE POLICY webhook_dispatcher ON webhooks TO %[1]s USING (true) WITH CHECK (true);
+		DROP POLICY IF EXISTS webhook_dispatcher ON webhook_deliveries;
+		CREATE POLICY webhook_dispatcher ON webhook_deliveries TO %[1]s USING (true) WITH CHECK (true)`,
+			pq.QuoteIdentifier(p.dispatcherRole))
+		if _, err := p.Conn.Exec(query); err != nil {
+			slog.Error("FAILED to grant webhook tables to the dispatcher role", "role", p.dispatcherRole, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	query = `
+	CREATE TABLE IF NOT EXISTS products (
+		id INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		qty INT NOT NULL,
+		updated_at TIMESTAMP NOT NULL DEFAULT now()
+	);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to auto-migrate products table", "error", err)
+		os.Exit(1)
+	}
+
+	query = `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id BIGSERIAL PRIMARY KEY,
+		routing_key TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT now()
+	)`
+	if _, err := p.Conn.Exec(query); err != nil {
+		slog.Error("FAILED to auto-migrate outbox table", "error", err)
+		os.Exit(1)
 	}
 }
 
-func (p *PostgresDB) CreateOrder(order *domain.Order) error {
-	query := `INSERT INTO orders (product_id, total_price, status, created_at)
-	          VALUES ($1, $2, $3, $4) RETURNING id`
-	return p.Conn.QueryRow(query, order.ProductID, order.TotalPrice, order.Status, order.CreatedAt).Scan(&order.ID)
+// DefaultTenant owns the orders created before tenants were introduced.
+const DefaultTenant = "default"
+
+// inTenant runs fn in a transaction scoped to the tenant of ctx, which
+// also sets app.tenant_id for the row-level security policy.
+func (p *PostgresDB) inTenant(ctx context.Context, fn func(tx *sql.Tx, tenantID string) error) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenantID); err != nil {
+		return err
+	}
+	if err := fn(tx, tenantID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (p *PostgresDB) GetOrdersByProductID(productID int) ([]*domain.Order, error) {
-	query := `SELECT id, product_id, total_price, status, created_at FROM orders WHERE product_id=$1`
-	rows, err := p.Conn.Query(query, productID)
+// CreateOrder inserts order into the tenant of ctx together with the event
+// of its initial status and the order.created deliveries to its webhooks.
+func (p *PostgresDB) CreateOrder(ctx context.Context, order *domain.Order) (_ *domain.OrderEvent, err error) {
+	ctx, span := startSpan(ctx, "create_order")
+	defer observe(span, "create_order", time.Now(), &err)
+
+	var ev *domain.OrderEvent
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		order.TenantID = tenantID
+		query := `INSERT INTO orders (tenant_id, product_id, customer_id, total_price, status, created_at)
+		          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, revision`
+		err := tx.QueryRowContext(ctx, query, tenantID, order.ProductID, order.CustomerID, order.TotalPrice, order.Status, order.CreatedAt).Scan(&order.ID, &order.Revision)
+		if err != nil {
+			return err
+		}
+		if ev, err = insertOrderEvent(ctx, tx, order); err != nil {
+			return err
+		}
+		return enqueueWebhookDeliveries(ctx, tx, domain.WebhookOrderCreated, order, ev)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return ev, nil
+}
 
-	var orders []*domain.Order
-	for rows.Next() {
-		o := &domain.Order{}
-		if err := rows.Scan(&o.ID, &o.ProductID, &o.TotalPrice, &o.Status, &o.CreatedAt); err != nil {
-			return nil, err
+const orderColumns = `id, tenant_id, product_id, customer_id, total_price, status, created_at, revision`
+
+func (p *PostgresDB) GetOrdersByProductID(ctx context.Context, productID int) (_ []*domain.Order, err error) {
+	ctx, span := startSpan(ctx, "get_orders_by_product")
+	defer observe(span, "get_orders_by_product", time.Now(), &err)
+
+	return p.queryOrders(ctx, `SELECT `+orderColumns+` FROM orders WHERE tenant_id=$1 AND product_id=$2 ORDER BY id`, productID)
+}
+
+func (p *PostgresDB) GetOrdersByCustomerID(ctx context.Context, customerID string) (_ []*domain.Order, err error) {
+	ctx, span := startSpan(ctx, "get_orders_by_customer")
+	defer observe(span, "get_orders_by_customer", time.Now(), &err)
+
+	return p.queryOrders(ctx, `SELECT `+orderColumns+` FROM orders WHERE tenant_id=$1 AND customer_id=$2 ORDER BY id`, customerID)
+}
+
+// queryOrders runs query with the tenant of ctx as its first argument.
+func (p *PostgresDB) queryOrders(ctx context.Context, query string, args ...interface{}) ([]*domain.Order, error) {
+	orders := []*domain.Order{}
+	err := p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		rows, err := tx.QueryContext(ctx, query, append([]interface{}{tenantID}, args...)...)
+		if err != nil {
+			return err
 		}
-		orders = append(orders, o)
-	}
+		defer rows.Close()
 
-	if orders == nil {
-		orders = []*domain.Order{}
+		for rows.Next() {
+			o := &domain.Order{}
+			if err := scanOrder(rows, o); err != nil {
+				return err
+			}
+			orders = append(orders, o)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return orders, nil
 }
 
-func (db *PostgresDB) UpdateOrderStatus(orderID int, status string) error {
-	_, err := db.Conn.Exec(`UPDATE orders SET status = $1 WHERE id = $2`, status, orderID)
+func scanOrder(row interface{ Scan(...interface{}) error }, o *domain.Order) error {
+	return row.Scan(&o.ID, &o.TenantID, &o.ProductID, &o.CustomerID, &o.TotalPrice, &o.Status, &o.CreatedAt, &o.Revision)
+}
+
+// GetOrderByID returns an order of the tenant of ctx, or sql.ErrNoRows.
+func (p *PostgresDB) GetOrderByID(ctx context.Context, orderID int) (_ *domain.Order, err error) {
+	ctx, span := startSpan(ctx, "get_order")
+	defer observe(span, "get_order", time.Now(), &err)
+
+	o := &domain.Order{}
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `SELECT ` + orderColumns + ` FROM orders WHERE tenant_id = $1 AND id = $2`
+		return scanOrder(tx.QueryRowContext(ctx, query, tenantID, orderID), o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UpdateOrderStatus sets the status of an order of the tenant of ctx and
+// returns the updated row, plus the event recording the change when the
+// status actually changed. Such a change is also queued for the webhooks
+// subscribed to webhookEvent. Cancelled orders are left alone; it returns
+// sql.ErrNoRows for them as for orders that do not exist.
+func (p *PostgresDB) UpdateOrderStatus(ctx context.Context, orderID int, status, webhookEvent string) (_ *domain.Order, _ *domain.OrderEvent, err error) {
+	ctx, span := startSpan(ctx, "update_order_status")
+	defer observe(span, "update_order_status", time.Now(), &err)
+
+	o := &domain.Order{}
+	var ev *domain.OrderEvent
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		var previous string
+		query := `SELECT status FROM orders WHERE tenant_id = $1 AND id = $2 AND status <> $3 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, tenantID, orderID, domain.OrderStatusCancelled).Scan(&previous); err != nil {
+			return err
+		}
+
+		query = `UPDATE orders SET status = $1, revision = revision + CASE WHEN status = $1 THEN 0 ELSE 1 END
+		          WHERE tenant_id = $2 AND id = $3 RETURNING ` + orderColumns
+		if err := scanOrder(tx.QueryRowContext(ctx, query, status, tenantID, orderID), o); err != nil {
+			return err
+		}
+		if previous == status {
+			return nil
+		}
+		var err error
+		if ev, err = insertOrderEvent(ctx, tx, o); err != nil {
+			return err
+		}
+		return enqueueWebhookDeliveries(ctx, tx, webhookEvent, o, ev)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return o, ev, nil
+}
+
+// insertOrderEvent takes the next seq of the tenant, which keeps its counter
+// row locked until tx ends. Event writers of a tenant therefore commit in
+// seq order; the counter is taken last so that it is held briefly.
+func insertOrderEvent(ctx context.Context, tx *sql.Tx, o *domain.Order) (*domain.OrderEvent, error) {
+	ev := &domain.OrderEvent{TenantID: o.TenantID, OrderID: o.ID, CustomerID: o.CustomerID, Status: o.Status}
+	query := `INSERT INTO order_event_sequences (tenant_id, last_seq) VALUES ($1, 1)
+	          ON CONFLICT (tenant_id) DO UPDATE SET last_seq = order_event_sequences.last_seq + 1
+	          RETURNING last_seq`
+	if err := tx.QueryRowContext(ctx, query, ev.TenantID).Scan(&ev.Seq); err != nil {
+		return nil, err
+	}
+	query = `INSERT INTO order_events (tenant_id, order_id, customer_id, status, seq)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	if err := tx.QueryRowContext(ctx, query, ev.TenantID, ev.OrderID, ev.CustomerID, ev.Status, ev.Seq).Scan(&ev.ID, &ev.CreatedAt); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// maxEventBatch bounds the events returned by one query.
+const maxEventBatch = 1000
+
+const orderEventColumns = `id, seq, tenant_id, order_id, customer_id, status, created_at`
+
+// GetOrderEvents returns up to 1000 events of an order of the tenant of ctx
+// with a seq above afterSeq, oldest first.
+func (p *PostgresDB) GetOrderEvents(ctx context.Context, orderID int, afterSeq int64) (_ []*domain.OrderEvent, err error) {
+	ctx, span := startSpan(ctx, "get_order_events")
+	defer observe(span, "get_order_events", time.Now(), &err)
+
+	return p.queryOrderEvents(ctx, `SELECT `+orderEventColumns+` FROM order_events
+		WHERE tenant_id = $1 AND order_id = $2 AND seq > $3 ORDER BY seq LIMIT $4`, orderID, afterSeq, maxEventBatch)
+}
+
+// GetCustomerOrderEvents returns up to 1000 events of the orders of a
+// customer of the tenant of ctx with a seq above afterSeq, oldest first.
+func (p *PostgresDB) GetCustomerOrderEvents(ctx context.Context, customerID string, afterSeq int64) (_ []*domain.OrderEvent, err error) {
+	ctx, span := startSpan(ctx, "get_customer_order_events")
+	defer observe(span, "get_customer_order_events", time.Now(), &err)
+
+	return p.queryOrderEvents(ctx, `SELECT `+orderEventColumns+` FROM order_events
+		WHERE tenant_id = $1 AND customer_id = $2 AND seq > $3 ORDER BY seq LIMIT $4`, customerID, afterSeq, maxEventBatch)
+}
+
+// LastOrderEventSeq returns the seq of the last committed event of the
+// tenant of ctx, or 0. Streams start after it to skip the history.
+func (p *PostgresDB) LastOrderEventSeq(ctx context.Context) (seq int64, err error) {
+	ctx, span := startSpan(ctx, "last_order_event_seq")
+	defer observe(span, "last_order_event_seq", time.Now(), &err)
+
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `SELECT last_seq FROM order_event_sequences WHERE tenant_id = $1`
+		err := tx.QueryRowContext(ctx, query, tenantID).Scan(&seq)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return seq, err
+}
+
+// queryOrderEvents runs query with the tenant of ctx as its first argument.
+func (p *PostgresDB) queryOrderEvents(ctx context.Context, query string, args ...interface{}) ([]*domain.OrderEvent, error) {
+	events := []*domain.OrderEvent{}
+	err := p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		rows, err := tx.QueryContext(ctx, query, append([]interface{}{tenantID}, args...)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			ev := &domain.OrderEvent{}
+			if err := rows.Scan(&ev.ID, &ev.Seq, &ev.TenantID, &ev.OrderID, &ev.CustomerID, &ev.Status, &ev.CreatedAt); err != nil {
+				return err
+			}
+			events = append(events, ev)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// UpsertProduct stores a product in the local catalog replica. Older
+// versions never overwrite newer ones, so out-of-order events are harmless.
+// A zero UpdatedAt is stored as -infinity, so that any product event
+// supersedes the row; a deleted product is only revived by a strictly newer
+// version.
+func (p *PostgresDB) UpsertProduct(ctx context.Context, prod *domain.Product) (err error) {
+	ctx, span := startSpan(ctx, "upsert_product")
+	defer observe(span, "upsert_product", time.Now(), &err)
+
+	query := `INSERT INTO products (id, name, price, qty, updated_at)
+	          VALUES ($1, $2, $3, $4, COALESCE($5, '-infinity'::timestamp))
+	          ON CONFLICT (id) DO UPDATE
+	          SET name = EXCLUDED.name, price = EXCLUDED.price, qty = EXCLUDED.qty,
+	              updated_at = EXCLUDED.updated_at, deleted_at = NULL
+	          WHERE products.updated_at < EXCLUDED.updated_at
+	             OR (products.updated_at = EXCLUDED.updated_at AND products.deleted_at IS NULL)`
+	version := sql.NullTime{Time: prod.UpdatedAt, Valid: !prod.UpdatedAt.IsZero()}
+	_, err = p.Conn.ExecContext(ctx, query, prod.ID, prod.Name, prod.Price, prod.Qty, version)
+	return err
+}
+
+// GetProductByID reads a product from the local catalog replica.
+// It returns nil without error when the product is unknown or deleted.
+func (p *PostgresDB) GetProductByID(ctx context.Context, id int) (_ *domain.Product, err error) {
+	ctx, span := startSpan(ctx, "get_product")
+	defer observe(span, "get_product", time.Now(), &err)
+
+	query := `SELECT id, name, price, qty, NULLIF(updated_at, '-infinity')
+	          FROM products WHERE id=$1 AND deleted_at IS NULL`
+	prod := &domain.Product{}
+	var version sql.NullTime
+	err = p.Conn.QueryRowContext(ctx, query, id).Scan(&prod.ID, &prod.Name, &prod.Price, &prod.Qty, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prod.UpdatedAt = version.Time
+	return prod, nil
+}
+
+// DeleteProduct replaces a product with a tombstone at the given version,
+// so that a redelivered older event cannot bring it back. Deletes older
+// than the stored version are ignored.
+func (p *PostgresDB) DeleteProduct(ctx context.Context, id int, version time.Time) (err error) {
+	ctx, span := startSpan(ctx, "delete_product")
+	defer observe(span, "delete_product", time.Now(), &err)
+
+	query := `INSERT INTO products (id, name, price, qty, updated_at, deleted_at)
+	          VALUES ($1, '', 0, 0, $2, $2)
+	          ON CONFLICT (id) DO UPDATE
+	          SET updated_at = EXCLUDED.updated_at, deleted_at = EXCLUDED.deleted_at
+	          WHERE products.updated_at <= EXCLUDED.updated_at`
+	_, err = p.Conn.ExecContext(ctx, query, id, version)
+	return err
+}
+
+// OutboxMessage is an event waiting in the outbox to be published.
+type OutboxMessage struct {
+	ID         int64
+	RoutingKey string
+	Payload    []byte
+	CreatedAt  time.Time
+}
+
+// InsertOutbox stores an event to be published later by DrainOutbox.
+func (p *PostgresDB) InsertOutbox(ctx context.Context, routingKey string, payload []byte) (err error) {
+	ctx, span := startSpan(ctx, "insert_outbox")
+	defer observe(span, "insert_outbox", time.Now(), &err)
+
+	_, err = p.Conn.ExecContext(ctx, `INSERT INTO outbox (routing_key, payload) VALUES ($1, $2)`, routingKey, payload)
 	return err
 }
+
+// DrainOutbox passes up to limit of the oldest outbox messages to publish
+// and deletes those it accepted. It stops at the first publish error. Rows
+// are locked with SKIP LOCKED so several instances can drain concurrently.
+func (p *PostgresDB) DrainOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (n int, err error) {
+	ctx, span := startSpan(ctx, "drain_outbox")
+	defer observe(span, "drain_outbox", time.Now(), &err)
+
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, routing_key, payload, created_at FROM outbox
+	          ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, err
+	}
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err = rows.Scan(&m.ID, &m.RoutingKey, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var publishErr error
+	for _, m := range msgs {
+		if publishErr = publish(m); publishErr != nil {
+			break
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM outbox WHERE id = $1`, m.ID); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, publishErr
+}
+
+func startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "postgres "+query, trace.SpanKindClient,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", query),
+	)
+}
+
+func observe(span trace.Span, query string, start time.Time, err *error) {
+	metrics.ObserveDB(query, start, *err)
+	if errors.Is(*err, sql.ErrNoRows) {
+		tracing.End(span, nil)
+		return
+	}
+	tracing.End(span, *err)
+}
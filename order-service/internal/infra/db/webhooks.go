@@ -0,0 +1,309 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, tenant_id, url, secret, events, active, consecutive_failures, disabled_at, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }, w *domain.Webhook) error {
+	return row.Scan(&w.ID, &w.TenantID, &w.URL, &w.Secret, pq.Array(&w.Events), &w.Active,
+		&w.ConsecutiveFailures, &w.DisabledAt, &w.CreatedAt, &w.UpdatedAt)
+}
+
+// CreateWebhook inserts w into the tenant of ctx.
+func (p *PostgresDB) CreateWebhook(ctx context.Context, w *domain.Webhook) (err error) {
+	ctx, span := startSpan(ctx, "create_webhook")
+	defer observe(span, "create_webhook", time.Now(), &err)
+
+	return p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `INSERT INTO webhooks (tenant_id, url, secret, events, active)
+		          VALUES ($1, $2, $3, $4, true) RETURNING ` + webhookColumns
+		return scanWebhook(tx.QueryRowContext(ctx, query, tenantID, w.URL, w.Secret, pq.Array(w.Events)), w)
+	})
+}
+
+// ListWebhooks returns the webhooks of the tenant of ctx.
+func (p *PostgresDB) ListWebhooks(ctx context.Context) (_ []*domain.Webhook, err error) {
+	ctx, span := startSpan(ctx, "list_webhooks")
+	defer observe(span, "list_webhooks", time.Now(), &err)
+
+	webhooks := []*domain.Webhook{}
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		rows, err := tx.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE tenant_id = $1 ORDER BY id`, tenantID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			w := &domain.Webhook{}
+			if err := scanWebhook(rows, w); err != nil {
+				return err
+			}
+			webhooks = append(webhooks, w)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook of the tenant of ctx, or sql.ErrNoRows.
+func (p *PostgresDB) GetWebhook(ctx context.Context, id int) (_ *domain.Webhook, err error) {
+	ctx, span := startSpan(ctx, "get_webhook")
+	defer observe(span, "get_webhook", time.Now(), &err)
+
+	w := &domain.Webhook{}
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE tenant_id = $1 AND id = $2`
+		return scanWebhook(tx.QueryRowContext(ctx, query, tenantID, id), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// UpdateWebhook locks a webhook of the tenant of ctx, lets apply change its
+// URL, events and state, and stores the result. It returns sql.ErrNoRows
+// for unknown webhooks.
+func (p *PostgresDB) UpdateWebhook(ctx context.Context, id int, apply func(w *domain.Webhook)) (_ *domain.Webhook, err error) {
+	ctx, span := startSpan(ctx, "update_webhook")
+	defer observe(span, "update_webhook", time.Now(), &err)
+
+	w := &domain.Webhook{}
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+		if err := scanWebhook(tx.QueryRowContext(ctx, query, tenantID, id), w); err != nil {
+			return err
+		}
+		apply(w)
+
+		query = `UPDATE webhooks
+		         SET url = $1, events = $2, active = $3, consecutive_failures = $4, disabled_at = $5, updated_at = now()
+		         WHERE tenant_id = $6 AND id = $7 RETURNING ` + webhookColumns
+		return scanWebhook(tx.QueryRowContext(ctx, query, w.URL, pq.Array(w.Events), w.Active,
+			w.ConsecutiveFailures, w.DisabledAt, tenantID, id), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// DeleteWebhook deletes a webhook of the tenant of ctx and its deliveries.
+// It returns sql.ErrNoRows for unknown webhooks.
+func (p *PostgresDB) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "delete_webhook")
+	defer observe(span, "delete_webhook", time.Now(), &err)
+
+	return p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// enqueueWebhookDeliveries queues the change of o recorded by ev for every
+// active webhook of its tenant subscribed to event. It runs in the
+// transaction making the change, so no committed change misses its
+// deliveries.
+func enqueueWebhookDeliveries(ctx context.Context, tx *sql.Tx, event string, o *domain.Order, ev *domain.OrderEvent) error {
+	payload, err := domain.NewWebhookPayload(event, o, ev)
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+	query := `INSERT INTO webhook_deliveries (tenant_id, webhook_id, event, payload)
+	          SELECT tenant_id, id, $2, $3 FROM webhooks
+	          WHERE tenant_id = $1 AND active AND $2 = ANY (events)`
+	_, err = tx.ExecContext(ctx, query, o.TenantID, event, payload)
+	return err
+}
+
+const deliveryColumns = `id, tenant_id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at`
+
+func deliveryFields(d *domain.WebhookDelivery) []interface{} {
+	return []interface{}{&d.ID, &d.TenantID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt}
+}
+
+// ListWebhookDeliveries returns up to limit deliveries of a webhook of the
+// tenant of ctx, newest first. An empty status matches every status and
+// beforeID, when positive, pages past the deliveries already seen.
+func (p *PostgresDB) ListWebhookDeliveries(ctx context.Context, webhookID int, status string, beforeID int64, limit int) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "list_webhook_deliveries")
+	defer observe(span, "list_webhook_deliveries", time.Now(), &err)
+
+	deliveries := []*domain.WebhookDelivery{}
+	err = p.inTenant(ctx, func(tx *sql.Tx, tenantID string) error {
+		query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		          WHERE tenant_id = $1 AND webhook_id = $2 AND ($3 = '' OR status = $3) AND ($4 <= 0 OR id < $4)
+		          ORDER BY id DESC LIMIT $5`
+		rows, err := tx.QueryContext(ctx, query, tenantID, webhookID, status, beforeID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			d := &domain.WebhookDelivery{}
+			if err := rows.Scan(deliveryFields(d)...); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimedDelivery is a due delivery together with the endpoint to send it to.
+type ClaimedDelivery struct {
+	domain.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// DeliveryResult is the outcome of one delivery attempt.
+type DeliveryResult struct {
+	DeliveryID     int64
+	WebhookID      int
+	Succeeded      bool
+	ResponseStatus int
+	Error          string
+	// GiveUp marks a failed delivery as failed for good; otherwise it is
+	// retried after RetryIn.
+	GiveUp  bool
+	RetryIn time.Duration
+	// DisableAfter is the number of consecutive failures that disables
+	// the webhook.
+	DisableAfter int
+}
+
+// errNoDispatcher is returned by the dispatcher queries of a PostgresDB
+// opened without Config.DispatcherUser.
+var errNoDispatcher = errors.New("no webhook dispatcher role configured")
+
+// asDispatcher runs fn in a transaction of the dispatcher role, which may
+// see the webhooks and deliveries of every tenant.
+func (p *PostgresDB) asDispatcher(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if p.dispatcher == nil {
+		return errNoDispatcher
+	}
+	tx, err := p.dispatcher.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ClaimWebhookDeliveries returns up to limit due deliveries of active
+// webhooks across all tenants and pushes their next attempt lease into the
+// future, so other replicas skip them and a crashed attempt is retried.
+func (p *PostgresDB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []ClaimedDelivery, err error) {
+	ctx, span := startSpan(ctx, "claim_webhook_deliveries")
+	defer observe(span, "claim_webhook_deliveries", time.Now(), &err)
+
+	var claimed []ClaimedDelivery
+	err = p.asDispatcher(ctx, func(tx *sql.Tx) error {
+		query := `UPDATE webhook_deliveries d
+		          SET next_attempt_at = now() + make_interval(secs => $2)
+		          FROM webhooks w
+		          WHERE w.id = d.webhook_id AND d.id IN (
+		              SELECT dd.id FROM webhook_deliveries dd JOIN webhooks ww ON ww.id = dd.webhook_id
+		              WHERE dd.status = 'pending' AND dd.next_attempt_at <= now() AND ww.active
+		              ORDER BY dd.next_attempt_at LIMIT $1
+		              FOR UPDATE OF dd SKIP LOCKED)
+		          RETURNING d.id, d.tenant_id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+		                    d.last_attempt_at, d.response_status, d.last_error, d.created_at, w.url, w.secret`
+		rows, err := tx.QueryContext(ctx, query, limit, lease.Seconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c ClaimedDelivery
+			if err := rows.Scan(append(deliveryFields(&c.WebhookDelivery), &c.URL, &c.Secret)...); err != nil {
+				return err
+			}
+			claimed = append(claimed, c)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// FinishWebhookDelivery records an attempt and updates the failure count of
+// its webhook. It reports whether this failure disabled the webhook.
+func (p *PostgresDB) FinishWebhookDelivery(ctx context.Context, r DeliveryResult) (disabled bool, err error) {
+	ctx, span := startSpan(ctx, "finish_webhook_delivery")
+	defer observe(span, "finish_webhook_delivery", time.Now(), &err)
+
+	err = p.asDispatcher(ctx, func(tx *sql.Tx) error {
+		if r.Succeeded {
+			query := `UPDATE webhook_deliveries
+			          SET status = 'succeeded', attempts = attempts + 1, last_attempt_at = now(), response_status = $2, last_error = ''
+			          WHERE id = $1`
+			if _, err := tx.ExecContext(ctx, query, r.DeliveryID, r.ResponseStatus); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1`, r.WebhookID)
+			return err
+		}
+
+		status := domain.DeliveryPending
+		if r.GiveUp {
+			status = domain.DeliveryFailed
+		}
+		query := `UPDATE webhook_deliveries
+		          SET status = $2, attempts = attempts + 1, last_attempt_at = now(), response_status = $3, last_error = $4,
+		              next_attempt_at = now() + make_interval(secs => $5)
+		          WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, r.DeliveryID, status, r.ResponseStatus, r.Error, r.RetryIn.Seconds()); err != nil {
+			return err
+		}
+
+		query = `UPDATE webhooks
+		         SET consecutive_failures = consecutive_failures + 1,
+		             active = active AND consecutive_failures + 1 < $2,
+		             disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $2 THEN now() ELSE disabled_at END
+		         WHERE id = $1
+		         RETURNING NOT active AND consecutive_failures = $2`
+		err := tx.QueryRowContext(ctx, query, r.WebhookID, r.DisableAfter).Scan(&disabled)
+		if err == sql.ErrNoRows {
+			// deleted while the attempt ran
+			return nil
+		}
+		return err
+	})
+	return disabled, err
+}
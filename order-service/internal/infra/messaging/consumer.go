@@ -0,0 +1,67 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ConsumerConfig controls how a subscription processes deliveries.
+type ConsumerConfig struct {
+	// Workers is the number of deliveries handled concurrently.
+	Workers int
+	// Prefetch caps the unacknowledged deliveries the broker sends to the
+	// subscription; it should be at least Workers.
+	Prefetch int
+	// MaxRetries is how often a delivery failing with a transient error
+	// is retried before it is dead-lettered.
+	MaxRetries int
+	// RetryDelay is how long a failed delivery waits in the retry queue.
+	RetryDelay time.Duration
+}
+
+func DefaultConsumerConfig() ConsumerConfig {
+	return ConsumerConfig{Workers: 8, Prefetch: 16, MaxRetries: 5, RetryDelay: 5 * time.Second}
+}
+
+// Handler processes one delivery. The delivery is acknowledged when it
+// returns nil, dead-lettered when the error is Permanent and retried later
+// otherwise.
+type Handler func(ctx context.Context, body []byte) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a malformed message.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+const (
+	retryCountHeader = "x-retry-count"
+	// errorHeader carries the last error of a dead-lettered message.
+	errorHeader = "x-error"
+)
+
+func retryCount(headers map[string]interface{}) int {
+	switch n := headers[retryCountHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
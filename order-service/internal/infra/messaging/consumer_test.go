@@ -0,0 +1,109 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/messaging"
+	"github.com/streadway/amqp"
+)
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("bad payload")
+	err := fmt.Errorf("handle: %w", messaging.Permanent(base))
+
+	if !messaging.IsPermanent(err) {
+		t.Fatal("Expected wrapped permanent error to be detected")
+	}
+	if !errors.Is(err, base) {
+		t.Fatal("Expected permanent error to unwrap to its cause")
+	}
+	if messaging.IsPermanent(base) {
+		t.Fatal("Expected plain error to be transient")
+	}
+	if messaging.Permanent(nil) != nil {
+		t.Fatal("Expected Permanent(nil) to be nil")
+	}
+}
+
+// fakeAcknowledger records how a delivery was settled.
+type fakeAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *fakeAcknowledger) Ack(uint64, bool) error { a.acked = true; return nil }
+
+func (a *fakeAcknowledger) Nack(_ uint64, _ bool, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(_ uint64, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func TestSettle(t *testing.T) {
+	cfg := messaging.ConsumerConfig{MaxRetries: 3, RetryDelay: 5 * time.Second}
+	transient := errors.New("db unavailable")
+
+	cases := []struct {
+		name        string
+		attempt     int32
+		err         error
+		sendErr     error
+		wantQueue   string
+		wantAck     bool
+		wantRequeue bool
+	}{
+		{name: "success", err: nil, wantAck: true},
+		{name: "transient", err: transient, wantQueue: "orders.retry", wantAck: true},
+		{name: "permanent", err: messaging.Permanent(errors.New("bad json")), wantQueue: "orders.dlq", wantAck: true},
+		{name: "out of retries", attempt: 3, err: transient, wantQueue: "orders.dlq", wantAck: true},
+		{name: "retry queue unavailable", err: transient, sendErr: errors.New("channel closed"), wantQueue: "orders.retry", wantRequeue: true},
+		{name: "dead-letter queue unavailable", err: messaging.Permanent(errors.New("bad json")), sendErr: errors.New("channel closed"), wantQueue: "orders.dlq", wantRequeue: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			m := amqp.Delivery{Acknowledger: ack, Body: []byte(`{}`), Headers: amqp.Table{"traceparent": "x"}}
+			if tc.attempt > 0 {
+				m.Headers["x-retry-count"] = tc.attempt
+			}
+
+			var sentTo string
+			var sent amqp.Publishing
+			send := func(queue string, msg amqp.Publishing) error {
+				sentTo, sent = queue, msg
+				return tc.sendErr
+			}
+			messaging.Settle(context.Background(), "orders", "order.updated.#", m, tc.err, cfg, send)
+
+			if sentTo != tc.wantQueue {
+				t.Errorf("sent to %q, want %q", sentTo, tc.wantQueue)
+			}
+			if ack.acked != tc.wantAck || ack.requeued != tc.wantRequeue || (ack.nacked && !tc.wantRequeue) {
+				t.Errorf("acked=%v nacked=%v requeued=%v, want ack=%v requeue=%v", ack.acked, ack.nacked, ack.requeued, tc.wantAck, tc.wantRequeue)
+			}
+			if sentTo == "" {
+				return
+			}
+			if string(sent.Body) != `{}` || sent.Headers["traceparent"] != "x" {
+				t.Errorf("Expected body and headers to be copied, got %s %v", sent.Body, sent.Headers)
+			}
+			switch tc.wantQueue {
+			case "orders.retry":
+				if sent.Headers["x-retry-count"] != int32(tc.attempt+1) || sent.Expiration != "5000" {
+					t.Errorf("retry copy has count %v and expiration %q", sent.Headers["x-retry-count"], sent.Expiration)
+				}
+			case "orders.dlq":
+				if sent.Headers["x-error"] == nil || sent.Expiration != "" {
+					t.Errorf("dead letter has error %v and expiration %q", sent.Headers["x-error"], sent.Expiration)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+package messaging
+
+// Settle exposes the acknowledgement logic of consumers to tests.
+var Settle = settle
@@ -1,31 +1,59 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultReconnectDelay is the pause between reconnect attempts.
+const DefaultReconnectDelay = 5 * time.Second
+
 type Publisher struct {
-	url         string
-	exchange    string
-	serviceName string
-	conn        *amqp.Connection
-	channel     *amqp.Channel
-	mutex       sync.Mutex
-	isClosed    bool
+	url            string
+	exchange       string
+	serviceName    string
+	reconnectDelay time.Duration
+	conn           *amqp.Connection
+	channel        *amqp.Channel
+	mutex          sync.Mutex
+	isClosed       bool
+
+	subscriptions   []subscription
+	stopped         bool
+	consumerTags    []string
+	consumers       sync.WaitGroup
+	activeConsumers atomic.Int32
+}
+
+// subscription is remembered so it can be set up again after a reconnect.
+type subscription struct {
+	routingKey string
+	handler    Handler
+	cfg        ConsumerConfig
 }
 
-func NewPublisher(url, exchange, serviceName string) (*Publisher, error) {
-	p := &Publisher{url: url, exchange: exchange, serviceName: serviceName}
+func NewPublisher(url, exchange, serviceName string, reconnectDelay time.Duration) (*Publisher, error) {
+	if reconnectDelay <= 0 {
+		reconnectDelay = DefaultReconnectDelay
+	}
+	p := &Publisher{url: url, exchange: exchange, serviceName: serviceName, reconnectDelay: reconnectDelay}
 	if err := p.connect(); err != nil {
 		return nil, err
 	}
-	go p.reconnectWatcher()
 	return p, nil
 }
 
@@ -52,37 +80,104 @@ func (p *Publisher) connect() error {
 
 	p.conn = conn
 	p.channel = ch
-	log.Println("CONNECTED to RabbitMQ and exchange declared:", p.exchange)
+	slog.Info("CONNECTED to RabbitMQ and exchange declared", "exchange", p.exchange)
+
+	go p.watch(conn, ch)
 	return nil
 }
 
-func (p *Publisher) reconnectWatcher() {
+// watch waits for conn or its channel to close and, unless the publisher
+// was closed, reconnects. A channel closed on its own is as fatal to the
+// consumers as a lost connection, so both are handled the same way.
+func (p *Publisher) watch(conn *amqp.Connection, ch *amqp.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	var err *amqp.Error
+	select {
+	case err = <-connClosed:
+	case err = <-chClosed:
+	}
+
+	p.mutex.Lock()
+	if p.isClosed {
+		p.mutex.Unlock()
+		return
+	}
+	if p.channel == ch {
+		p.channel = nil
+	}
+	p.mutex.Unlock()
+	_ = conn.Close()
+
+	slog.Warn("RabbitMQ connection CLOSED, reconnecting", "error", err)
 	for {
-		if p.conn == nil {
-			time.Sleep(5 * time.Second)
+		time.Sleep(p.reconnectDelay)
+		if err := p.connect(); err != nil {
+			p.mutex.Lock()
+			closed := p.isClosed
+			p.mutex.Unlock()
+			if closed {
+				return
+			}
+			slog.Warn("Retry reconnect FAILED, retrying", "error", err)
 			continue
 		}
-		errChan := make(chan *amqp.Error)
-		p.conn.NotifyClose(errChan)
-		err := <-errChan
-		if err != nil {
-			log.Printf("RabbitMQ connection closed: %v. Reconnecting...", err)
-			for {
-				time.Sleep(5 * time.Second)
-				if err := p.connect(); err == nil {
-					log.Println("RECONNECTED to RabbitMQ")
-					break
-				}
-				log.Println("Retry reconnect FAILED, retrying...")
-			}
+		slog.Info("RECONNECTED to RabbitMQ")
+		p.resubscribe()
+		return
+	}
+}
+
+// resubscribe sets up the subscriptions again on a new channel. A failure
+// closes that channel, so watch tries again.
+func (p *Publisher) resubscribe() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stopped || p.channel == nil {
+		return
+	}
+	p.consumerTags = nil
+	for _, sub := range p.subscriptions {
+		if err := p.subscribe(sub); err != nil {
+			slog.Error("FAILED to resubscribe", "routing_key", sub.routingKey, "error", err)
+			return
 		}
 	}
+	slog.Info("RabbitMQ consumers RESUBSCRIBED", "count", len(p.subscriptions))
+}
+
+// Check reports whether the connection and channel are open.
+func (p *Publisher) Check(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conn == nil || p.conn.IsClosed() {
+		return fmt.Errorf("connection closed")
+	}
+	if p.channel == nil {
+		return fmt.Errorf("channel closed")
+	}
+	return nil
 }
 
-func (p *Publisher) Publish(routingKey string, data map[string]interface{}, requestId ...string) error {
+// ActiveConsumers returns the number of subscriptions still receiving deliveries.
+func (p *Publisher) ActiveConsumers() int {
+	return int(p.activeConsumers.Load())
+}
+
+// Publish sends data to the exchange and propagates the trace context of
+// ctx in the message headers.
+func (p *Publisher) Publish(ctx context.Context, routingKey string, data map[string]interface{}, requestId ...string) (err error) {
+	ctx, span := tracing.Start(ctx, routingKey+" publish", trace.SpanKindProducer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", p.exchange),
+		attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	if p.channel == nil {
+		metrics.PublishTotal.WithLabelValues(routingKey, "error").Inc()
 		return fmt.Errorf("channel not initialized")
 	}
 	if len(requestId) > 0 {
@@ -94,12 +189,16 @@ func (p *Publisher) Publish(routingKey string, data map[string]interface{}, requ
 		return fmt.Errorf("FAILED to marshal data: %v", err)
 	}
 
+	headers := amqp.Table{}
+	tracing.InjectAMQP(ctx, headers)
+
 	err = p.channel.Publish(
 		p.exchange,
 		routingKey,
 		false,
 		false,
 		amqp.Publishing{
+			Headers:      headers,
 			ContentType:  "application/json",
 			DeliveryMode: amqp.Persistent,
 			Body:         body,
@@ -107,65 +206,217 @@ func (p *Publisher) Publish(routingKey string, data map[string]interface{}, requ
 		},
 	)
 	if err != nil {
+		metrics.PublishTotal.WithLabelValues(routingKey, "error").Inc()
 		return fmt.Errorf("FAILED to publish: %v", err)
 	}
+	metrics.PublishTotal.WithLabelValues(routingKey, "ok").Inc()
 
-	log.Printf("[RequestID: %s] Message PUBLISHED to exchange '%s' with key '%s'", data["requestId"], p.exchange, routingKey)
+	logging.FromContext(ctx).Debug("Message PUBLISHED", "exchange", p.exchange, "routing_key", routingKey)
 	return nil
 }
 
-func (p *Publisher) Subscribe(routingKey string, handler func([]byte)) error {
+// Subscribe consumes routingKey on a durable per-service queue with
+// cfg.Workers concurrent handlers. Each handler receives a context carrying
+// the producer's trace context; the delivery is acknowledged only after the
+// handler returns. Transient failures go through a delayed retry queue and
+// permanent ones, or those out of retries, to a dead-letter queue. The
+// subscription is set up again whenever the connection is re-established.
+func (p *Publisher) Subscribe(routingKey string, handler Handler, cfg ConsumerConfig) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	sub := subscription{routingKey: routingKey, handler: handler, cfg: cfg}
+	if err := p.subscribe(sub); err != nil {
+		return err
+	}
+	p.subscriptions = append(p.subscriptions, sub)
+	return nil
+}
+
+// subscribe must be called with p.mutex held.
+func (p *Publisher) subscribe(sub subscription) error {
+	routingKey, handler, cfg := sub.routingKey, sub.handler, sub.cfg
 	if p.channel == nil {
 		return fmt.Errorf("channel not initialized")
 	}
 
-	queueName := fmt.Sprintf("%s.%s", routingKey, p.serviceName)
-	_, err := p.channel.QueueDeclare(queueName, true, false, false, false, nil)
-	if err != nil {
-		return fmt.Errorf("queue declare FAILED: %v", err)
+	// a trailing wildcard does not change the queue name, so widening a
+	// binding to cover tenant suffixes keeps the existing queue
+	queueName := fmt.Sprintf("%s.%s", strings.TrimSuffix(routingKey, ".#"), p.serviceName)
+	if err := p.declareQueues(queueName); err != nil {
+		return err
 	}
-
 	if err := p.channel.QueueBind(queueName, routingKey, p.exchange, false, nil); err != nil {
 		return fmt.Errorf("queue bind FAILED: %v", err)
 	}
 
-	msgs, err := p.channel.Consume(queueName, "", false, false, false, false, nil)
+	// prefetch applies to consumers started after it on this channel
+	if err := p.channel.Qos(cfg.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("qos FAILED: %v", err)
+	}
+	tag := fmt.Sprintf("%s.%d", queueName, len(p.consumerTags))
+	msgs, err := p.channel.Consume(queueName, tag, false, false, false, false, nil)
 	if err != nil {
 		return fmt.Errorf("queue consume FAILED: %v", err)
 	}
+	p.consumerTags = append(p.consumerTags, tag)
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	var pool sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		pool.Add(1)
+		go func() {
+			defer pool.Done()
+			for msg := range msgs {
+				p.handle(queueName, routingKey, msg, handler, cfg)
+			}
+		}()
+	}
 
+	p.consumers.Add(1)
+	p.activeConsumers.Add(1)
 	go func() {
-		for msg := range msgs {
-			func(m amqp.Delivery) {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("Panic while handling message: %v", r)
-						m.Nack(false, true)
-					}
-				}()
-				if err := safeHandler(handler, m.Body); err != nil {
-					m.Nack(false, true)
-				} else {
-					m.Ack(false)
-				}
-			}(msg)
-		}
+		defer p.consumers.Done()
+		defer p.activeConsumers.Add(-1)
+		pool.Wait()
 	}()
 
 	return nil
 }
 
-func safeHandler(h func([]byte), body []byte) (err error) {
+// declareQueues declares queueName with its dead-letter queue <queue>.dlq
+// and its retry queue <queue>.retry, whose messages return to queueName
+// when they expire. queueName keeps the arguments it always had, since
+// redeclaring an existing queue with different ones fails; dead letters
+// and retries are published to their queues explicitly instead.
+func (p *Publisher) declareQueues(queueName string) error {
+	if _, err := p.channel.QueueDeclare(queueName, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("queue declare FAILED: %v", err)
+	}
+	if _, err := p.channel.QueueDeclare(queueName+".dlq", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("queue declare FAILED: %v", err)
+	}
+	_, err := p.channel.QueueDeclare(queueName+".retry", true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queueName,
+	})
+	if err != nil {
+		return fmt.Errorf("queue declare FAILED: %v", err)
+	}
+	return nil
+}
+
+func (p *Publisher) handle(queueName, routingKey string, m amqp.Delivery, handler Handler, cfg ConsumerConfig) {
+	ctx := tracing.ExtractAMQP(context.Background(), m.Headers)
+	ctx, span := tracing.Start(ctx, routingKey+" process", trace.SpanKindConsumer,
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+	)
+	err := safeHandler(ctx, handler, m.Body)
+	tracing.End(span, err)
+
+	settle(ctx, queueName, routingKey, m, err, cfg, p.send)
+}
+
+// settle acknowledges m according to the outcome err of its handler. Failed
+// deliveries are copied to the retry or dead-letter queue of queueName with
+// send before they are acked; if that fails they are requeued rather than
+// lost.
+func settle(ctx context.Context, queueName, routingKey string, m amqp.Delivery, err error, cfg ConsumerConfig, send func(queue string, msg amqp.Publishing) error) {
+	if err == nil {
+		metrics.ConsumeTotal.WithLabelValues(routingKey, "ack").Inc()
+		_ = m.Ack(false)
+		return
+	}
+
+	attempt := retryCount(m.Headers)
+	queue, outcome := queueName+".retry", "retry"
+	msg := copyDelivery(m)
+	if IsPermanent(err) || attempt >= cfg.MaxRetries {
+		logging.FromContext(ctx).Error("Message DEAD-LETTERED", "routing_key", routingKey, "attempt", attempt, "error", err)
+		queue, outcome = queueName+".dlq", "dead_letter"
+		msg.Headers[errorHeader] = err.Error()
+	} else {
+		logging.FromContext(ctx).Warn("Message handling FAILED, retrying", "routing_key", routingKey, "attempt", attempt, "error", err)
+		msg.Headers[retryCountHeader] = int32(attempt + 1)
+		msg.Expiration = strconv.FormatInt(cfg.RetryDelay.Milliseconds(), 10)
+	}
+
+	if serr := send(queue, msg); serr != nil {
+		logging.FromContext(ctx).Error("FAILED to move message, requeueing it", "queue", queue, "error", serr)
+		metrics.ConsumeTotal.WithLabelValues(routingKey, "requeue").Inc()
+		_ = m.Nack(false, true)
+		return
+	}
+	metrics.ConsumeTotal.WithLabelValues(routingKey, outcome).Inc()
+	_ = m.Ack(false)
+}
+
+func copyDelivery(m amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Body:         m.Body,
+		Timestamp:    m.Timestamp,
+	}
+}
+
+// send publishes msg to queue through the default exchange.
+func (p *Publisher) send(queue string, msg amqp.Publishing) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.channel == nil {
+		return fmt.Errorf("channel not initialized")
+	}
+	return p.channel.Publish("", queue, false, false, msg)
+}
+
+// safeHandler turns a handler panic into a permanent error, since a
+// message that crashes the handler would most likely crash it again.
+func safeHandler(ctx context.Context, h Handler, body []byte) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("handler panic: %v", r)
+			err = Permanent(fmt.Errorf("handler panic: %v", r))
 		}
 	}()
-	h(body)
-	return nil
+	return h(ctx, body)
+}
+
+// StopConsuming cancels every subscription and waits, until ctx is done,
+// for the handlers of already delivered messages to finish.
+func (p *Publisher) StopConsuming(ctx context.Context) error {
+	p.mutex.Lock()
+	p.stopped = true
+	if p.channel != nil {
+		for _, tag := range p.consumerTags {
+			if err := p.channel.Cancel(tag, false); err != nil {
+				slog.Error("FAILED to cancel consumer", "consumer", tag, "error", err)
+			}
+		}
+	}
+	p.consumerTags = nil
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.consumers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		slog.Info("RabbitMQ consumers STOPPED")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for consumers: %w", ctx.Err())
+	}
 }
 
 func (p *Publisher) Close() {
@@ -178,5 +429,5 @@ func (p *Publisher) Close() {
 	if p.conn != nil {
 		_ = p.conn.Close()
 	}
-	log.Println("RabbitMQ connection CLOSED")
+	slog.Info("RabbitMQ connection CLOSED")
 }
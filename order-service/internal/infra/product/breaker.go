@@ -0,0 +1,127 @@
+package product
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker trips after a run of consecutive failures, rejects calls
+// while open, and lets a limited number of probes through once the open
+// timeout has elapsed.
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+
+	mutex    sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	inFlight int
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration, halfOpenProbes int) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 5
+	}
+	if halfOpenProbes <= 0 {
+		halfOpenProbes = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenProbes:   halfOpenProbes,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one Success or Failure.
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.inFlight = 0
+		fallthrough
+	case stateHalfOpen:
+		if b.inFlight >= b.halfOpenProbes {
+			return false
+		}
+		b.inFlight++
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.state = stateClosed
+	b.failures = 0
+	b.inFlight = 0
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == stateHalfOpen {
+		b.trip()
+		return
+	}
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.trip()
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.state = stateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.inFlight = 0
+}
+
+// Release gives back an allowed call without recording an outcome, e.g.
+// when the caller cancelled before the upstream answered.
+func (b *CircuitBreaker) Release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == stateHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+// State returns the current breaker state as a string.
+func (b *CircuitBreaker) State() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state.String()
+}
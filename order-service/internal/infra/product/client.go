@@ -0,0 +1,232 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNotFound is returned when the product-service has no such product.
+	ErrNotFound = errors.New("product not found")
+	// ErrUnavailable is returned when the product-service cannot be reached,
+	// keeps failing after retries, or the circuit breaker is open.
+	ErrUnavailable = errors.New("product service unavailable")
+)
+
+type Config struct {
+	BaseURL string
+	// Timeout bounds one attempt, TotalTimeout a call with all its retries.
+	Timeout          time.Duration
+	TotalTimeout     time.Duration
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	MaxBackoff       time.Duration
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int
+	MaxIdleConns     int
+}
+
+func DefaultConfig(baseURL string) Config {
+	return Config{
+		BaseURL:          baseURL,
+		Timeout:          2 * time.Second,
+		TotalTimeout:     3 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      50 * time.Millisecond,
+		MaxBackoff:       500 * time.Millisecond,
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenProbes:   1,
+		MaxIdleConns:     200,
+	}
+}
+
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *CircuitBreaker
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg: cfg,
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+			// otelhttp creates a span per attempt and injects traceparent
+			Transport: otelhttp.NewTransport(&http.Transport{
+				MaxIdleConns:        cfg.MaxIdleConns,
+				MaxIdleConnsPerHost: cfg.MaxIdleConns,
+			}),
+		},
+		breaker: NewCircuitBreaker(cfg.FailureThreshold, cfg.OpenTimeout, cfg.HalfOpenProbes),
+	}
+}
+
+// BreakerState exposes the circuit breaker state for diagnostics.
+func (c *Client) BreakerState() string {
+	return c.breaker.State()
+}
+
+// GetProduct fetches a product, retrying 5xx responses and timeouts with
+// exponential backoff and jitter. Retries stop once what is left of
+// TotalTimeout could not cover another attempt.
+func (c *Client) GetProduct(ctx context.Context, productID int, requestID string) (_ *domain.Product, err error) {
+	ctx, span := tracing.Start(ctx, "product.GetProduct", trace.SpanKindInternal, attribute.Int("product.id", productID))
+	defer func() {
+		if errors.Is(err, ErrNotFound) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	if !c.breaker.Allow() {
+		metrics.ProductClientRequests.WithLabelValues("circuit_open").Inc()
+		return nil, fmt.Errorf("%w: circuit open", ErrUnavailable)
+	}
+
+	parent := ctx
+	if c.cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.TotalTimeout)
+		defer cancel()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := c.backoff(attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff+c.cfg.Timeout {
+				break
+			}
+			if err := sleepCtx(ctx, backoff); err != nil {
+				break
+			}
+		}
+
+		start := time.Now()
+		prod, retryable, err := c.get(ctx, productID, requestID)
+		outcome := outcomeOf(err)
+		metrics.ProductClientRequests.WithLabelValues(outcome).Inc()
+		metrics.ProductClientDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+		if err == nil {
+			c.breaker.Success()
+			return prod, nil
+		}
+		if parent.Err() != nil {
+			// The caller gave up; that says nothing about upstream health.
+			c.breaker.Release()
+			return nil, parent.Err()
+		}
+		if errors.Is(err, ErrUnavailable) {
+			c.breaker.Failure()
+			return nil, err
+		}
+		if !retryable {
+			// The upstream answered; it is healthy even if the product is missing.
+			c.breaker.Success()
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	if parent.Err() != nil {
+		c.breaker.Release()
+		return nil, parent.Err()
+	}
+	c.breaker.Failure()
+	return nil, fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+func (c *Client) get(ctx context.Context, productID int, requestID string) (*domain.Product, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/products/%d", c.cfg.BaseURL, productID), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("X-Request-ID", requestID)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		if isRetryable(err) {
+			return nil, true, err
+		}
+		// refused connections and failed lookups will not heal by retrying
+		return nil, false, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, false, ErrNotFound
+	case res.StatusCode >= 500:
+		return nil, true, fmt.Errorf("upstream status %d", res.StatusCode)
+	case res.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var prod domain.Product
+	if err := json.NewDecoder(res.Body).Decode(&prod); err != nil {
+		return nil, false, fmt.Errorf("failed to decode product: %w", err)
+	}
+	return &prod, false, nil
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// full jitter
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func outcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	default:
+		return "error"
+	}
+}
+
+// isRetryable reports whether a failed request timed out.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package product_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/product"
+)
+
+func newTestClient(url string) *product.Client {
+	cfg := product.DefaultConfig(url)
+	cfg.BaseBackoff = time.Millisecond
+	cfg.MaxBackoff = 2 * time.Millisecond
+	cfg.FailureThreshold = 2
+	cfg.OpenTimeout = time.Hour
+	return product.NewClient(cfg)
+}
+
+func TestGetProduct_RetriesOn5xx(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"id":1,"name":"A","price":50,"qty":10}`))
+	}))
+	defer srv.Close()
+
+	prod, err := newTestClient(srv.URL).GetProduct(context.Background(), 1, "req-1")
+	if err != nil {
+		t.Fatalf("GetProduct failed: %v", err)
+	}
+	if prod.Price != 50 {
+		t.Errorf("Expected Price 50, got %f", prod.Price)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestGetProduct_NotFoundIsNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestClient(srv.URL).GetProduct(context.Background(), 1, "req-1")
+	if !errors.Is(err, product.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestGetProduct_BreakerOpens(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetProduct(context.Background(), 1, "req-1"); !errors.Is(err, product.ErrUnavailable) {
+			t.Fatalf("Expected ErrUnavailable, got %v", err)
+		}
+	}
+	if c.BreakerState() != "open" {
+		t.Fatalf("Expected breaker open, got %s", c.BreakerState())
+	}
+
+	before := atomic.LoadInt32(&calls)
+	if _, err := c.GetProduct(context.Background(), 1, "req-1"); !errors.Is(err, product.ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != before {
+		t.Errorf("Expected no upstream call while breaker is open")
+	}
+}
+
+func TestGetProduct_ConnectionFailureIsNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	_, err := newTestClient(srv.URL).GetProduct(context.Background(), 1, "req-1")
+	if !errors.Is(err, product.ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+}
+
+func TestGetProduct_TotalTimeout(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	cfg := product.DefaultConfig(srv.URL)
+	cfg.Timeout = 40 * time.Millisecond
+	cfg.TotalTimeout = 100 * time.Millisecond
+	cfg.MaxRetries = 5
+	cfg.BaseBackoff = time.Millisecond
+	cfg.MaxBackoff = 2 * time.Millisecond
+
+	start := time.Now()
+	_, err := product.NewClient(cfg).GetProduct(context.Background(), 1, "req-1")
+	if !errors.Is(err, product.ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected the lookup to end within its total timeout, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected 2 calls before the budget ran out, got %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Bus broadcasts messages to every replica subscribed to a channel. Delivery
+// is best effort: subscribers are told through resync when messages may have
+// been lost and must catch up from the source of truth.
+type Bus interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe calls handle for every message published on channel until
+	// ctx is done. It returns once the subscription is active.
+	Subscribe(ctx context.Context, channel string, handle func(payload []byte), resync func()) error
+}
+
+var (
+	_ Bus = (*RedisBus)(nil)
+	_ Bus = (*LocalBus)(nil)
+)
+
+// LocalBus delivers messages within the process only. It serves single
+// replica deployments without Redis.
+type LocalBus struct {
+	mutex    sync.RWMutex
+	nextID   int
+	handlers map[string]map[int]func([]byte)
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{handlers: make(map[string]map[int]func([]byte))}
+}
+
+func (b *LocalBus) Publish(_ context.Context, channel string, payload []byte) error {
+	b.mutex.RLock()
+	handlers := make([]func([]byte), 0, len(b.handlers[channel]))
+	for _, handle := range b.handlers[channel] {
+		handlers = append(handlers, handle)
+	}
+	b.mutex.RUnlock()
+
+	for _, handle := range handlers {
+		handle(payload)
+	}
+	return nil
+}
+
+func (b *LocalBus) Subscribe(ctx context.Context, channel string, handle func([]byte), _ func()) error {
+	b.mutex.Lock()
+	id := b.nextID
+	b.nextID++
+	if b.handlers[channel] == nil {
+		b.handlers[channel] = make(map[int]func([]byte))
+	}
+	b.handlers[channel][id] = handle
+	b.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mutex.Lock()
+		delete(b.handlers[channel], id)
+		b.mutex.Unlock()
+	}()
+	return nil
+}
@@ -0,0 +1,40 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/pubsub"
+)
+
+func TestLocalBus_DeliversUntilUnsubscribed(t *testing.T) {
+	bus := pubsub.NewLocalBus()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan string, 2)
+	if err := bus.Subscribe(ctx, "order-events", func(p []byte) { received <- string(p) }, func() {}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	_ = bus.Publish(context.Background(), "other", []byte("ignored"))
+	_ = bus.Publish(context.Background(), "order-events", []byte("hello"))
+	if got := <-received; got != "hello" {
+		t.Fatalf("Expected hello, got %q", got)
+	}
+
+	cancel()
+	// the unsubscribe runs asynchronously once ctx is done
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		_ = bus.Publish(context.Background(), "order-events", []byte("late"))
+		select {
+		case <-received:
+			time.Sleep(5 * time.Millisecond)
+			continue
+		default:
+		}
+		return
+	}
+	t.Fatal("handler still subscribed after ctx was cancelled")
+}
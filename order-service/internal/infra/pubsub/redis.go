@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const reconnectDelay = time.Second
+
+// RedisBus broadcasts through Redis pub/sub, reaching every replica
+// connected to the same Redis deployment.
+type RedisBus struct {
+	client redis.UniversalClient
+}
+
+func NewRedisBus(client redis.UniversalClient) *RedisBus {
+	return &RedisBus{client: client}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.client.Publish(ctx, channel, payload).Err()
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, channel string, handle func([]byte), resync func()) error {
+	ps := b.client.Subscribe(ctx, channel)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return err
+	}
+
+	go func() {
+		defer ps.Close()
+		for {
+			msg, err := ps.ReceiveMessage(ctx)
+			if err == nil {
+				handle([]byte(msg.Payload))
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("Pub/sub subscription INTERRUPTED", "channel", channel, "error", err)
+			// Ping reconnects and resubscribes; messages published in the
+			// meantime are gone, so subscribers resync afterwards.
+			for ps.Ping(ctx) != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(reconnectDelay):
+				}
+			}
+			slog.Info("Pub/sub subscription RESTORED", "channel", channel)
+			resync()
+		}
+	}()
+	return nil
+}
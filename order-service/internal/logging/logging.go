@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
+)
+
+type ctxKey struct{}
+
+// ParseLevel converts debug, info, warn or error to a slog.Level.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// Setup installs a JSON logger writing to w as the slog default. Output of
+// the standard log package goes through it as well.
+func Setup(w io.Writer, level slog.Level) *slog.Logger {
+	logger := slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or the
+// default logger, annotated with the trace and span IDs of the span in ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	if !ok {
+		logger = slog.Default()
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.With("trace_id", traceID, "span_id", tracing.SpanID(ctx))
+	}
+	return logger
+}
+
+// With returns a copy of ctx whose logger carries the extra attributes.
+func With(ctx context.Context, args ...any) context.Context {
+	logger, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	if !ok {
+		logger = slog.Default()
+	}
+	return WithLogger(ctx, logger.With(args...))
+}
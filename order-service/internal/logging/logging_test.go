@@ -0,0 +1,42 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+)
+
+func TestFromContext_CarriesRequestAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+	logging.Setup(&buf, slog.LevelInfo)
+
+	ctx := logging.With(context.Background(), "request_id", "abc")
+	logging.FromContext(ctx).Debug("hidden")
+	logging.FromContext(ctx).Info("visible")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected exactly one JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "visible" || entry["request_id"] != "abc" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		got, err := logging.ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	ProductClientRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "product_client_requests_total",
+		Help:      "Product-service calls by outcome (ok, not_found, error, circuit_open).",
+	}, []string{"outcome"})
+
+	ProductClientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "product_client_request_duration_seconds",
+		Help:      "Latency of individual product-service HTTP attempts.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Postgres query latency by query name and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query", "result"})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by keyspace and result (hit, miss, error).",
+	}, []string{"keyspace", "result"})
+
+	PublishTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messaging_publish_total",
+		Help:      "AMQP publishes by routing key and result.",
+	}, []string{"routing_key", "result"})
+
+	ConsumeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messaging_consume_total",
+		Help:      "AMQP deliveries by routing key and result (ack, nack).",
+	}, []string{"routing_key", "result"})
+
+	PoolSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_submissions_total",
+		Help:      "Work submitted to a worker pool by result (queued, blocked, spilled, shed, timeout, inline).",
+	}, []string{"pool", "result"})
+
+	PoolBusyWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_busy_workers",
+		Help:      "Workers of a pool currently running a task.",
+	}, []string{"pool"})
+
+	PoolBlockDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_pool_block_duration_seconds",
+		Help:      "Time submitters waited for room in a full worker queue.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"pool"})
+
+	RateLimitRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_requests_total",
+		Help:      "Rate limiter decisions by backend and result (allowed, limited, error).",
+	}, []string{"backend", "result"})
+
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result (succeeded, retrying, failed).",
+	}, []string{"result"})
+
+	WebhookDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Latency of webhook delivery attempts.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// Cache keyspaces.
+const (
+	KeyspaceProduct        = "product"
+	KeyspaceProductL1      = "product_l1"
+	KeyspaceOrdersProduct  = "orders_product"
+	KeyspaceOrdersCustomer = "orders_customer"
+)
+
+// RegisterQueueDepth exposes the current length and capacity of a worker queue.
+func RegisterQueueDepth(queue string, depth, capacity func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "worker_queue_depth",
+		Help:        "Items waiting in a worker queue.",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 { return float64(depth()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "worker_queue_capacity",
+		Help:        "Capacity of a worker queue.",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 { return float64(capacity()) })
+}
+
+// RegisterGauge exposes an arbitrary value computed at scrape time.
+func RegisterGauge(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
+
+// ObserveDB records the duration and result of a Postgres query.
+func ObserveDB(query string, start time.Time, err error) {
+	DBQueryDuration.WithLabelValues(query, result(err)).Observe(time.Since(start).Seconds())
+}
+
+// CacheResult records a cache lookup.
+func CacheResult(keyspace string, hit bool, err error) {
+	switch {
+	case err != nil:
+		CacheRequests.WithLabelValues(keyspace, "error").Inc()
+	case hit:
+		CacheRequests.WithLabelValues(keyspace, "hit").Inc()
+	default:
+		CacheRequests.WithLabelValues(keyspace, "miss").Inc()
+	}
+}
+
+// RateLimitResult records a rate limiter decision.
+func RateLimitResult(backend string, allowed bool) {
+	if allowed {
+		RateLimitRequests.WithLabelValues(backend, "allowed").Inc()
+		return
+	}
+	RateLimitRequests.WithLabelValues(backend, "limited").Inc()
+}
+
+// RateLimitError records a rate limiter backend failure.
+func RateLimitError(backend string) {
+	RateLimitRequests.WithLabelValues(backend, "error").Inc()
+}
+
+// Handler serves the Prometheus scrape endpoint.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+)
+
+// AccessLogMiddleware logs one entry per request with its status code and
+// latency. Install it after TracingMiddleware so entries carry the trace ID.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(r.Context()).Log(r.Context(), level, "HTTP request",
+			"status", rec.status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+	})
+}
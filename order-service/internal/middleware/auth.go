@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+)
+
+// Auth authenticates requests with bearer JWTs. A nil Verifier disables
+// authentication and scope checks altogether.
+type Auth struct {
+	Verifier *auth.Verifier
+}
+
+// Authenticate rejects requests without a valid bearer token with 401 and
+// stores the principal in the context of the others.
+func (a *Auth) Authenticate(next http.Handler) http.Handler {
+	if a == nil || a.Verifier == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		principal, err := a.Verifier.Verify(r.Context(), token)
+		if err != nil {
+			logging.FromContext(r.Context()).Info("Token REJECTED", "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = logging.With(ctx, "subject", principal.Subject)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require wraps a handler so that it answers 403 unless the principal was
+// granted every one of scopes.
+func (a *Auth) Require(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil || a.Verifier == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				writeError(w, http.StatusUnauthorized, "missing bearer token")
+				return
+			}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+					writeError(w, http.StatusForbidden, "missing scope "+scope)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeError mirrors the controller error body.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"statusCode": status, "message": message})
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecation marks the responses of superseded routes with a Deprecation
+// header (RFC 9745), a Sunset header (RFC 8594) when a removal date is set,
+// and a Link to the same path under SuccessorPrefix.
+type Deprecation struct {
+	Since           time.Time
+	Sunset          time.Time
+	SuccessorPrefix string
+}
+
+func (d *Deprecation) Middleware(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Deprecation", deprecation)
+		if sunset != "" {
+			h.Set("Sunset", sunset)
+		}
+		h.Add("Link", "<"+d.SuccessorPrefix+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,33 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+)
+
+func TestDeprecation_Headers(t *testing.T) {
+	d := &middleware.Deprecation{
+		Since:           time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:          time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC),
+		SuccessorPrefix: "/v1",
+	}
+	h := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/product/1", nil))
+
+	want := map[string]string{
+		"Deprecation": "@1793491200",
+		"Sunset":      "Sat, 01 May 2027 00:00:00 GMT",
+		"Link":        `</v1/orders/product/1>; rel="successor-version"`,
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s: expected %q, got %q", name, value, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/ratelimit"
+)
+
+// RateLimit throttles clients with a token bucket each. Clients are told
+// apart by API key, then authenticated customer, then IP address. A nil
+// Limiter disables rate limiting.
+//
+// IPLimiter, when set, holds a second bucket per IP address that PerIP
+// charges before authentication, so requests with bad tokens are throttled
+// too.
+type RateLimit struct {
+	Limiter      ratelimit.Limiter
+	IPLimiter    ratelimit.Limiter
+	APIKeyHeader string
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For
+	// entry, i.e. the address seen by the proxy in front of the service.
+	TrustForwardedFor bool
+}
+
+// Middleware answers 429 with Retry-After once a client's bucket is empty
+// and sets the RateLimit-* headers on every response. Requests are let
+// through when the limiter fails.
+func (rl *RateLimit) Middleware(next http.Handler) http.Handler {
+	if rl == nil || rl.Limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var apiKey string
+		if rl.APIKeyHeader != "" {
+			apiKey = r.Header.Get(rl.APIKeyHeader)
+		}
+		res := rl.AllowClient(r.Context(), apiKey, rl.requestIP(r))
+		respond(res, next, w, r)
+	})
+}
+
+// PerIP is Middleware for the IPLimiter, keyed by IP address alone. It goes
+// in front of authentication.
+func (rl *RateLimit) PerIP(next http.Handler) http.Handler {
+	if rl == nil || rl.IPLimiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond(rl.AllowIP(r.Context(), rl.requestIP(r)), next, w, r)
+	})
+}
+
+// respond calls next unless res is an empty bucket, in which case it
+// answers 429. A nil res lets the request through.
+func respond(res *ratelimit.Result, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if res == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", Seconds(res.Reset))
+	if !res.Allowed {
+		h.Set("Retry-After", Seconds(res.RetryAfter))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// AllowClient takes a token from the bucket of the client named by apiKey,
+// the principal of ctx or ip, in that order. It returns nil when rate
+// limiting is disabled or the limiter fails.
+func (rl *RateLimit) AllowClient(ctx context.Context, apiKey, ip string) *ratelimit.Result {
+	if rl == nil || rl.Limiter == nil {
+		return nil
+	}
+	return take(ctx, rl.Limiter, rl.clientKey(ctx, apiKey, ip))
+}
+
+// AllowIP takes a token from the bucket of the IP address ip. It returns
+// nil when there is no IPLimiter or it fails.
+func (rl *RateLimit) AllowIP(ctx context.Context, ip string) *ratelimit.Result {
+	if rl == nil || rl.IPLimiter == nil {
+		return nil
+	}
+	// not "ip:", which is the per-client bucket of anonymous clients
+	return take(ctx, rl.IPLimiter, "addr:"+ip)
+}
+
+func take(ctx context.Context, limiter ratelimit.Limiter, key string) *ratelimit.Result {
+	res, err := limiter.Allow(ctx, key)
+	if err != nil {
+		logging.FromContext(ctx).Error("Rate limit check FAILED", "error", err)
+		return nil
+	}
+	return &res
+}
+
+func (rl *RateLimit) clientKey(ctx context.Context, apiKey, ip string) string {
+	if apiKey != "" {
+		// the key itself must not end up in Redis
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	if p := auth.FromContext(ctx); p != nil && p.Subject != "" {
+		return "customer:" + p.Subject
+	}
+	return "ip:" + ip
+}
+
+func (rl *RateLimit) requestIP(r *http.Request) string {
+	return rl.ClientIP(r.Header.Get("X-Forwarded-For"), r.RemoteAddr)
+}
+
+// ClientIP returns the IP address of a client connected from remoteAddr
+// or, with TrustForwardedFor, the last entry of forwardedFor.
+func (rl *RateLimit) ClientIP(forwardedFor, remoteAddr string) string {
+	if rl.TrustForwardedFor && forwardedFor != "" {
+		parts := strings.Split(forwardedFor, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// Seconds rounds d up to whole seconds, as the RateLimit-* and Retry-After
+// headers expect.
+func Seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/ratelimit"
+)
+
+func TestRateLimit_Middleware(t *testing.T) {
+	// a negligible rate so the bucket does not refill during the test
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 0.001, Burst: 2}, 100)
+	rl := &middleware.RateLimit{Limiter: limiter, APIKeyHeader: "X-API-Key"}
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders/product/1", nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rec := send("")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: expected RateLimit-Remaining %s, got %q", i, wantRemaining, got)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: expected RateLimit-Limit 2, got %q", i, got)
+		}
+	}
+
+	rec := send("")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the bucket is empty, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After on 429")
+	}
+
+	// an API key gets its own bucket even from the same address
+	if rec := send("key-1"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected API key client to be allowed, got %d", rec.Code)
+	}
+}
+
+func TestRateLimit_PerIPThrottlesBadTokens(t *testing.T) {
+	verifier, err := auth.NewVerifier(auth.Config{HS256Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &middleware.Auth{Verifier: verifier}
+	rl := &middleware.RateLimit{
+		Limiter:   ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 0.001, Burst: 10}, 100),
+		IPLimiter: ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 0.001, Burst: 2}, 100),
+	}
+	h := rl.PerIP(a.Authenticate(rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	send := func(addr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/orders/product/1", nil)
+		req.RemoteAddr = addr + ":4321"
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := send("10.0.0.1"); code != http.StatusUnauthorized {
+			t.Fatalf("request %d: expected 401, got %d", i, code)
+		}
+	}
+	if code := send("10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected bad tokens to be throttled, got %d", code)
+	}
+	if code := send("10.0.0.2"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected another address to get its own bucket, got %d", code)
+	}
+}
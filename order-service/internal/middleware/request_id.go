@@ -2,9 +2,9 @@ package middleware
 
 import (
 	"context"
-	"log"
 	"net/http"
 
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
 	"github.com/google/uuid"
 )
 
@@ -12,6 +12,8 @@ type ctxKey string
 
 const RequestIDKey ctxKey = "requestID"
 
+// RequestIDMiddleware assigns every request an ID and stores it, together
+// with a logger carrying the request ID, method and path, in the context.
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := r.Header.Get("X-Request-ID")
@@ -20,9 +22,9 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+		ctx = logging.With(ctx, "request_id", requestID, "method", r.Method, "path", r.URL.Path)
 		w.Header().Set("X-Request-ID", requestID)
 
-		log.Printf("[RequestID: %s] %s %s", requestID, r.Method, r.URL.Path)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
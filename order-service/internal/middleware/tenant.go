@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+)
+
+// Tenant resolves the tenant of each request. Authenticated requests get the
+// tenant from the JWT claim named Claim; the Header may only repeat it, so a
+// token cannot be used to reach another tenant. The Header selects the
+// tenant only for requests without a principal, i.e. when auth is disabled.
+// Requests naming neither fall back to Default, and are rejected with 400
+// when it is empty.
+type Tenant struct {
+	Header  string
+	Claim   string
+	Default string
+}
+
+// Middleware stores the resolved tenant in the request context. Install it
+// after Auth.Authenticate so the token claims are available.
+func (t *Tenant) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, status, err := t.Resolve(r.Context(), r.Header.Get(t.Header))
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+
+		ctx := tenant.WithTenant(r.Context(), id)
+		ctx = logging.With(ctx, "tenant", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Resolve picks the tenant from the principal in ctx and the value of the
+// tenant header, returning the HTTP status to answer with on failure.
+func (t *Tenant) Resolve(ctx context.Context, header string) (string, int, error) {
+	p := auth.FromContext(ctx)
+	var claim string
+	if p != nil && t.Claim != "" {
+		claim, _ = p.Claims[t.Claim].(string)
+	}
+
+	id := t.Default
+	switch {
+	case claim != "" && header != "" && header != claim:
+		return "", http.StatusForbidden, fmt.Errorf("%s does not match the token's tenant", t.Header)
+	case claim != "":
+		id = claim
+	case header != "" && p != nil:
+		return "", http.StatusForbidden, fmt.Errorf("%s is only accepted with a token naming the same tenant", t.Header)
+	case header != "":
+		id = header
+	}
+
+	if id == "" {
+		return "", http.StatusBadRequest, fmt.Errorf("%s header is required", t.Header)
+	}
+	if err := tenant.Validate(id); err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	return id, 0, nil
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/auth"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTenant_Resolve(t *testing.T) {
+	resolver := &middleware.Tenant{Header: "X-Tenant-ID", Claim: "tenant_id", Default: "default"}
+
+	tests := []struct {
+		name          string
+		header        string
+		claim         string
+		authenticated bool
+		wantStatus    int
+		wantTenant    string
+	}{
+		{name: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "header without auth", header: "shop-a", wantStatus: http.StatusOK, wantTenant: "shop-a"},
+		{name: "token without claim", authenticated: true, wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "header with token without claim", header: "shop-a", authenticated: true, wantStatus: http.StatusForbidden},
+		{name: "claim", claim: "shop-b", wantStatus: http.StatusOK, wantTenant: "shop-b"},
+		{name: "header matches claim", header: "shop-b", claim: "shop-b", wantStatus: http.StatusOK, wantTenant: "shop-b"},
+		{name: "header contradicts claim", header: "shop-a", claim: "shop-b", wantStatus: http.StatusForbidden},
+		{name: "invalid", header: "shop.a", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := resolver.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = tenant.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/orders/product/1", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if tt.claim != "" || tt.authenticated {
+				p := &auth.Principal{Subject: "alice", Claims: jwt.MapClaims{}}
+				if tt.claim != "" {
+					p.Claims["tenant_id"] = tt.claim
+				}
+				req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got != tt.wantTenant {
+				t.Fatalf("Expected tenant %q, got %q", tt.wantTenant, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package openapi serves the OpenAPI 3.1 document of the order-service and
+// a documentation page rendering it.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/swaggest/swgui"
+	"github.com/swaggest/swgui/v5emb"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI document.
+func Spec() []byte {
+	return spec
+}
+
+// Handler serves the OpenAPI document.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+}
+
+// DocsHandler serves a Swagger UI page for the document at /openapi.json
+// at /docs, and the Swagger UI assets embedded in the binary below /docs/.
+func DocsHandler() http.Handler {
+	return v5emb.NewHandlerWithConfig(swgui.Config{
+		Title:       "Order Service API",
+		SwaggerJSON: "/openapi.json",
+		BasePath:    "/docs/",
+		SettingsUI:  map[string]string{"persistAuthorization": "true"},
+	})
+}
@@ -0,0 +1,188 @@
+package openapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/controller"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/health"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/openapi"
+	"github.com/gorilla/mux"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+type operation struct {
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]response `json:"responses"`
+}
+
+type response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Example json.RawMessage `json:"example"`
+}
+
+type document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Webhooks   map[string]map[string]operation `json:"webhooks"`
+	Components struct {
+		Responses map[string]response `json:"responses"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) document {
+	t.Helper()
+	var doc document
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Fatalf("Expected OpenAPI 3.1.0, got %q", doc.OpenAPI)
+	}
+	return doc
+}
+
+func newRouter() *mux.Router {
+	return controller.NewRouter(nil, health.NewChecker(time.Second), &middleware.Auth{}, &middleware.RateLimit{}, &middleware.Tenant{}, &middleware.Deprecation{SuccessorPrefix: "/v1"})
+}
+
+func TestSpec_DescribesEveryRoute(t *testing.T) {
+	doc := loadSpec(t)
+	router := newRouter()
+
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, m := range methods {
+			registered[m+" "+path] = true
+			if _, ok := doc.Paths[path][strings.ToLower(m)]; !ok {
+				t.Errorf("%s %s is not described in openapi.json", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is described but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestSpec_ExamplesMatchSchemas(t *testing.T) {
+	doc := loadSpec(t)
+
+	raw, err := jsonschema.UnmarshalJSON(bytes.NewReader(openapi.Spec()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	if err := compiler.AddResource("openapi.json", raw); err != nil {
+		t.Fatal(err)
+	}
+
+	checked := 0
+	check := func(name string, pointer []string, media map[string]mediaType) {
+		for contentType, m := range media {
+			if m.Example == nil {
+				continue
+			}
+			loc := append(append([]string{}, pointer...), "content", contentType, "schema")
+			schema, err := compiler.Compile("openapi.json#" + jsonPointer(loc))
+			if err != nil {
+				t.Errorf("%s: compile schema: %v", name, err)
+				continue
+			}
+			example, err := jsonschema.UnmarshalJSON(bytes.NewReader(m.Example))
+			if err != nil {
+				t.Errorf("%s: decode example: %v", name, err)
+				continue
+			}
+			if err := schema.Validate(example); err != nil {
+				t.Errorf("%s: example does not match schema: %v", name, err)
+			}
+			checked++
+		}
+	}
+
+	for _, section := range []struct {
+		name string
+		ops  map[string]map[string]operation
+	}{{"paths", doc.Paths}, {"webhooks", doc.Webhooks}} {
+		for path, ops := range section.ops {
+			for method, op := range ops {
+				name := strings.ToUpper(method) + " " + path
+				base := []string{section.name, path, method}
+				if op.RequestBody != nil {
+					check(name+" request", append(base, "requestBody"), op.RequestBody.Content)
+				}
+				for code, resp := range op.Responses {
+					if resp.Ref != "" {
+						if _, ok := doc.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]; !ok {
+							t.Errorf("%s %s: unknown response %s", name, code, resp.Ref)
+						}
+						continue
+					}
+					check(name+" "+code, append(base, "responses", code), resp.Content)
+				}
+			}
+		}
+	}
+	for name, resp := range doc.Components.Responses {
+		check("response "+name, []string{"components", "responses", name}, resp.Content)
+	}
+
+	if checked == 0 {
+		t.Fatal("no examples were checked")
+	}
+}
+
+func TestDocs_Served(t *testing.T) {
+	router := newRouter()
+	for path, contentType := range map[string]string{
+		"/openapi.json":        "application/json",
+		"/docs":                "text/html",
+		"/docs/swagger-ui.css": "text/css",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), contentType) {
+			t.Errorf("GET %s: got %d %q", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// jsonPointer escapes the reference tokens of an RFC 6901 pointer.
+func jsonPointer(tokens []string) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(tok))
+	}
+	return b.String()
+}
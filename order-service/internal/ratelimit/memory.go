@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryLimiter keeps buckets in process, so each replica enforces its own
+// limit. At most maxKeys buckets are kept; full buckets are dropped first
+// since they are indistinguishable from new ones.
+type MemoryLimiter struct {
+	cfg     Config
+	maxKeys int
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryLimiter(cfg Config, maxKeys int) *MemoryLimiter {
+	return &MemoryLimiter{
+		cfg:     cfg,
+		maxKeys: maxKeys,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	now := l.now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxKeys {
+			l.evict(now)
+		}
+		b = &bucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	metrics.RateLimitResult("memory", allowed)
+	return l.cfg.result(allowed, b.tokens), nil
+}
+
+func (l *MemoryLimiter) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(l.cfg.Burst), b.tokens+elapsed*l.cfg.Rate)
+}
+
+// evict drops full buckets, or an arbitrary one when none is full.
+func (l *MemoryLimiter) evict(now time.Time) {
+	for k, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.cfg.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+	if len(l.buckets) < l.maxKeys {
+		return
+	}
+	for k := range l.buckets {
+		delete(l.buckets, k)
+		break
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Config describes a token bucket: clients may send Burst requests at once
+// and Rate requests per second after that.
+type Config struct {
+	Rate  float64
+	Burst int
+}
+
+// Validate checks the bucket parameters.
+func (c Config) Validate() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate limit: rate must be positive")
+	}
+	if c.Burst <= 0 {
+		return fmt.Errorf("rate limit: burst must be positive")
+	}
+	return nil
+}
+
+// Result is the outcome of taking one token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket of key.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// result turns the tokens left in a bucket into a Result.
+func (c Config) result(allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     c.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     c.duration(float64(c.Burst) - tokens),
+	}
+	if !allowed {
+		res.RetryAfter = c.duration(1 - tokens)
+	}
+	return res
+}
+
+// duration is the time needed to refill tokens.
+func (c Config) duration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / c.Rate * float64(time.Second))
+}
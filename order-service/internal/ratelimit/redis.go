@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "ratelimit:"
+
+// tokenBucketScript refills and takes from the bucket in KEYS[1] atomically.
+// It uses the Redis clock so replicas with skewed clocks share one bucket.
+// ARGV: rate per second, burst. Returns {allowed, tokens left}.
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter shares buckets between replicas through Redis. While Redis
+// is unreachable it answers from the fallback limiter instead.
+type RedisLimiter struct {
+	client   redis.Scripter
+	cfg      Config
+	fallback Limiter
+	degraded atomic.Bool
+}
+
+func NewRedisLimiter(client redis.Scripter, cfg Config, fallback Limiter) *RedisLimiter {
+	return &RedisLimiter{client: client, cfg: cfg, fallback: fallback}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	res, err := l.take(ctx, key)
+	if err != nil {
+		metrics.RateLimitError("redis")
+		if l.fallback == nil {
+			return Result{}, err
+		}
+		if !l.degraded.Swap(true) {
+			slog.Warn("Rate limiter FALLING BACK to memory", "error", err)
+		}
+		return l.fallback.Allow(ctx, key)
+	}
+	if l.degraded.Swap(false) {
+		slog.Info("Rate limiter RECOVERED, using Redis")
+	}
+	metrics.RateLimitResult("redis", res.Allowed)
+	return res, nil
+}
+
+func (l *RedisLimiter) take(ctx context.Context, key string) (Result, error) {
+	vals, err := tokenBucketScript.Run(ctx, l.client, []string{keyPrefix + key}, l.cfg.Rate, l.cfg.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(vals) != 2 {
+		return Result{}, fmt.Errorf("rate limit script returned %d values", len(vals))
+	}
+	allowed, _ := vals[0].(int64)
+	raw, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return l.cfg.result(allowed == 1, tokens), nil
+}
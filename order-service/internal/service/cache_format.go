@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/cache"
+)
+
+// cacheFormatVersion marks the layout of cached orders and products, which
+// is independent of the domain structs and the API responses. Bump it when
+// a cached struct changes incompatibly: entries of another version read as
+// misses and are rebuilt from Postgres.
+const cacheFormatVersion = 1
+
+type cachedOrder struct {
+	Version    int       `json:"v"`
+	ID         int       `json:"id"`
+	TenantID   string    `json:"tenantId"`
+	ProductID  int       `json:"productId"`
+	CustomerID string    `json:"customerId"`
+	TotalPrice float64   `json:"totalPrice"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Revision   int64     `json:"revision"`
+}
+
+func newCachedOrder(o *domain.Order) cachedOrder {
+	return cachedOrder{
+		Version:    cacheFormatVersion,
+		ID:         o.ID,
+		TenantID:   o.TenantID,
+		ProductID:  o.ProductID,
+		CustomerID: o.CustomerID,
+		TotalPrice: o.TotalPrice,
+		Status:     o.Status,
+		CreatedAt:  o.CreatedAt,
+		Revision:   o.Revision,
+	}
+}
+
+// cachedOrderField keys a cached order by its revision, so an older copy
+// never replaces a newer one in the cached lists.
+func cachedOrderField(o *domain.Order) cache.HashField {
+	return cache.HashField{Revision: o.Revision, Value: newCachedOrder(o)}
+}
+
+func decodeCachedOrder(data []byte) (*domain.Order, error) {
+	var c cachedOrder
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Version != cacheFormatVersion {
+		return nil, fmt.Errorf("cached order has format %d, want %d", c.Version, cacheFormatVersion)
+	}
+	return &domain.Order{
+		ID:         c.ID,
+		TenantID:   c.TenantID,
+		ProductID:  c.ProductID,
+		CustomerID: c.CustomerID,
+		TotalPrice: c.TotalPrice,
+		Status:     c.Status,
+		CreatedAt:  c.CreatedAt,
+		Revision:   c.Revision,
+	}, nil
+}
+
+type cachedProduct struct {
+	Version   int       `json:"v"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Qty       int       `json:"qty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func newCachedProduct(p *domain.Product) cachedProduct {
+	return cachedProduct{
+		Version:   cacheFormatVersion,
+		ID:        p.ID,
+		Name:      p.Name,
+		Price:     p.Price,
+		Qty:       p.Qty,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+func decodeCachedProduct(data []byte) (*domain.Product, error) {
+	var c cachedProduct
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Version != cacheFormatVersion {
+		return nil, fmt.Errorf("cached product has format %d, want %d", c.Version, cacheFormatVersion)
+	}
+	return &domain.Product{ID: c.ID, Name: c.Name, Price: c.Price, Qty: c.Qty, UpdatedAt: c.UpdatedAt}, nil
+}
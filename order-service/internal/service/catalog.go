@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/messaging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+)
+
+const (
+	ProductCreatedKey = "product.created"
+	ProductUpdatedKey = "product.updated"
+	ProductDeletedKey = "product.deleted"
+)
+
+type productEvent struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Price     float64    `json:"price"`
+	Qty       int        `json:"qty"`
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+	Timestamp *time.Time `json:"timestamp"`
+	RequestID string     `json:"requestId"`
+}
+
+// version picks the most specific timestamp the producer sent so that
+// replayed or reordered events cannot roll the replica back.
+func (e productEvent) version() time.Time {
+	for _, t := range []*time.Time{e.UpdatedAt, e.Timestamp, e.CreatedAt} {
+		if t != nil && !t.IsZero() {
+			return *t
+		}
+	}
+	return time.Now()
+}
+
+// ListenProductEvents keeps the local products replica in sync with the
+// product-service.
+func (s *OrderService) ListenProductEvents() error {
+	for _, key := range []string{ProductCreatedKey, ProductUpdatedKey} {
+		if err := s.RMQ.Subscribe(key, s.handleProductUpserted, s.opts.Consumer); err != nil {
+			return err
+		}
+	}
+	return s.RMQ.Subscribe(ProductDeletedKey, s.handleProductDeleted, s.opts.Consumer)
+}
+
+func (s *OrderService) handleProductUpserted(ctx context.Context, body []byte) error {
+	var msg productEvent
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return messaging.Permanent(fmt.Errorf("decode product event: %w", err))
+	}
+	if msg.ID <= 0 {
+		return messaging.Permanent(fmt.Errorf("product event without id"))
+	}
+
+	prod := &domain.Product{
+		ID:        msg.ID,
+		Name:      msg.Name,
+		Price:     msg.Price,
+		Qty:       msg.Qty,
+		UpdatedAt: msg.version(),
+	}
+	ctx = logging.With(ctx, "request_id", msg.RequestID, "product_id", msg.ID)
+	if err := s.Db.UpsertProduct(ctx, prod); err != nil {
+		return fmt.Errorf("upsert product %d: %w", msg.ID, err)
+	}
+	s.products.Invalidate(msg.ID)
+	_ = s.Cache.Delete(ctx, productCacheKey(msg.ID))
+
+	logging.FromContext(ctx).Info("Product SYNCED to local catalog")
+	return nil
+}
+
+func (s *OrderService) handleProductDeleted(ctx context.Context, body []byte) error {
+	var msg productEvent
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return messaging.Permanent(fmt.Errorf("decode product.deleted: %w", err))
+	}
+	if msg.ID <= 0 {
+		return messaging.Permanent(fmt.Errorf("product.deleted without id"))
+	}
+
+	ctx = logging.With(ctx, "request_id", msg.RequestID, "product_id", msg.ID)
+	if err := s.Db.DeleteProduct(ctx, msg.ID, msg.version()); err != nil {
+		return fmt.Errorf("delete product %d: %w", msg.ID, err)
+	}
+	s.products.Invalidate(msg.ID)
+	_ = s.Cache.Delete(ctx, productCacheKey(msg.ID))
+
+	logging.FromContext(ctx).Info("Product REMOVED from local catalog")
+	return nil
+}
+
+func productCacheKey(productID int) string {
+	return fmt.Sprintf("product:%d", productID)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+)
+
+// orderEventsChannel is the pub/sub channel that carries applied order
+// events to every replica.
+const orderEventsChannel = "order-events"
+
+// EventFilter selects the events of one order or, when OrderID is zero, of
+// the orders of one customer.
+type EventFilter struct {
+	OrderID    int
+	CustomerID string
+}
+
+func (f EventFilter) key(tenantID string) string {
+	if f.OrderID != 0 {
+		return "order:" + tenantID + ":" + strconv.Itoa(f.OrderID)
+	}
+	return "customer:" + tenantID + ":" + f.CustomerID
+}
+
+// eventSub is woken when a stream may have new events. The events
+// themselves are always read from Postgres in seq order: the bus may deliver
+// them out of order, and an event sent ahead of an earlier one of the same
+// stream would make the stream skip that one.
+type eventSub struct {
+	wake chan struct{}
+}
+
+func (s *eventSub) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// orderWatchers wakes the streams of this replica for the order events
+// received from the bus.
+type orderWatchers struct {
+	mutex sync.Mutex
+	subs  map[string]map[*eventSub]struct{}
+}
+
+func newOrderWatchers() *orderWatchers {
+	return &orderWatchers{subs: make(map[string]map[*eventSub]struct{})}
+}
+
+func (w *orderWatchers) subscribe(key string) (*eventSub, func()) {
+	sub := &eventSub{wake: make(chan struct{}, 1)}
+
+	w.mutex.Lock()
+	if w.subs[key] == nil {
+		w.subs[key] = make(map[*eventSub]struct{})
+	}
+	w.subs[key][sub] = struct{}{}
+	w.mutex.Unlock()
+
+	return sub, func() {
+		w.mutex.Lock()
+		delete(w.subs[key], sub)
+		if len(w.subs[key]) == 0 {
+			delete(w.subs, key)
+		}
+		w.mutex.Unlock()
+	}
+}
+
+// notify wakes the streams of the order and customer of ev.
+func (w *orderWatchers) notify(ev *domain.OrderEvent) {
+	keys := []string{EventFilter{OrderID: ev.OrderID}.key(ev.TenantID)}
+	if ev.CustomerID != "" {
+		keys = append(keys, EventFilter{CustomerID: ev.CustomerID}.key(ev.TenantID))
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, key := range keys {
+		for sub := range w.subs[key] {
+			sub.signal()
+		}
+	}
+}
+
+// resync tells every stream to catch up, after the bus may have lost events.
+func (w *orderWatchers) resync() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, subs := range w.subs {
+		for sub := range subs {
+			sub.signal()
+		}
+	}
+}
+
+// broadcast sends an event that was just committed to the streams of every
+// replica. When the bus fails, at least the streams of this replica get it.
+func (s *OrderService) broadcast(ctx context.Context, ev *domain.OrderEvent) {
+	if ev == nil {
+		return
+	}
+	payload, err := json.Marshal(ev)
+	if err == nil {
+		err = s.bus.Publish(ctx, orderEventsChannel, payload)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("FAILED to broadcast order event", "event_id", ev.ID, "seq", ev.Seq, "error", err)
+		s.watchers.notify(ev)
+	}
+}
+
+func (s *OrderService) receiveEvent(payload []byte) {
+	var ev domain.OrderEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		slog.Warn("Order event DISCARDED", "error", err)
+		return
+	}
+	s.watchers.notify(&ev)
+}
+
+// StopWatching ends every event stream so that servers can shut down
+// without waiting for long-lived connections.
+func (s *OrderService) StopWatching() {
+	s.stopOnce.Do(func() { close(s.stopping) })
+}
+
+// StreamOrderEvents calls send with the events matching filter in the
+// tenant of ctx whose seq is above afterSeq, in seq order. With afterSeq
+// zero, an order stream replays the whole history of the order while a
+// customer stream starts with the next event. Every EventHeartbeat, send is called with nil
+// so idle connections stay open. It returns when ctx is done, StopWatching
+// is called or send fails.
+func (s *OrderService) StreamOrderEvents(ctx context.Context, filter EventFilter, afterSeq int64, send func(*domain.OrderEvent) error) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	sub, unsubscribe := s.watchers.subscribe(filter.key(tenantID))
+	defer unsubscribe()
+
+	last := afterSeq
+	if afterSeq == 0 && filter.OrderID == 0 {
+		if last, err = s.Db.LastOrderEventSeq(ctx); err != nil {
+			return err
+		}
+	}
+
+	// catchUp sends the stored events after last. Once an event is
+	// committed, so are all events of its tenant with a lower seq, so
+	// nothing below last can still show up.
+	catchUp := func() error {
+		for {
+			events, err := s.storedEvents(ctx, filter, last)
+			if err != nil || len(events) == 0 {
+				return err
+			}
+			for _, ev := range events {
+				if err := send(ev); err != nil {
+					return err
+				}
+				last = ev.Seq
+			}
+		}
+	}
+	if err := catchUp(); err != nil {
+		return err
+	}
+
+	var heartbeat <-chan time.Time
+	if s.opts.EventHeartbeat > 0 {
+		ticker := time.NewTicker(s.opts.EventHeartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return nil
+		case <-sub.wake:
+			if err := catchUp(); err != nil {
+				return err
+			}
+		case <-heartbeat:
+			if err := send(nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *OrderService) storedEvents(ctx context.Context, filter EventFilter, afterSeq int64) ([]*domain.OrderEvent, error) {
+	if filter.OrderID != 0 {
+		return s.Db.GetOrderEvents(ctx, filter.OrderID, afterSeq)
+	}
+	return s.Db.GetCustomerOrderEvents(ctx, filter.CustomerID, afterSeq)
+}
+
+// errWatchDone ends a watch from inside its send callback.
+var errWatchDone = errors.New("watch done")
+
+// WatchOrder calls send with the current state of an order of the tenant of
+// ctx and then with every status change, until the order is cancelled, ctx
+// is done, StopWatching is called or send fails.
+func (s *OrderService) WatchOrder(ctx context.Context, orderID int, send func(*domain.Order) error) error {
+	// Read the history before the order so that every event after it is
+	// at most as old as the state sent first.
+	history, err := s.Db.GetOrderEvents(ctx, orderID, 0)
+	if err != nil {
+		return err
+	}
+	var afterSeq int64
+	if len(history) > 0 {
+		afterSeq = history[len(history)-1].Seq
+	}
+
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if err := send(order); err != nil || order.Status == domain.OrderStatusCancelled {
+		return err
+	}
+
+	err = s.StreamOrderEvents(ctx, EventFilter{OrderID: orderID}, afterSeq, func(ev *domain.OrderEvent) error {
+		if ev == nil || ev.Status == order.Status {
+			return nil
+		}
+		next := *order
+		next.Status = ev.Status
+		order = &next
+		if err := send(order); err != nil {
+			return err
+		}
+		if order.Status == domain.OrderStatusCancelled {
+			return errWatchDone
+		}
+		return nil
+	})
+	if errors.Is(err, errWatchDone) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+)
+
+// ProductLookup exposes the L1 product cache to tests in service_test.
+type ProductLookup = productLookup
+
+func NewProductLookup(load func(ctx context.Context, productID int, requestID string) (*domain.Product, error), ttl, staleTTL time.Duration, maxItems int, now func() time.Time) *ProductLookup {
+	l := newProductLookup(load, ttl, staleTTL, maxItems)
+	l.now = now
+	return l
+}
+
+// Generations reports how many products have a generation of their own.
+func (l *ProductLookup) Generations() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.generations)
+}
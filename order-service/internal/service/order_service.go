@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,236 +16,550 @@ import (
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/cache"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/db"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/messaging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/product"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/pubsub"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
 	"github.com/dandiagusm/microservices-product-order/order-service/internal/middleware"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tenant"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/workerpool"
+)
+
+// Order event routing keys. Events are published as <key>.<tenant ID>.
+const (
+	OrderCreatedKey   = "order.created"
+	OrderUpdatedKey   = "order.updated"
+	OrderCancelledKey = "order.cancelled"
+)
+
+// ErrOverloaded is returned when the service sheds work because a worker
+// pool is full.
+var ErrOverloaded = workerpool.ErrOverloaded
+
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrOrderCancelled = errors.New("order already cancelled")
 )
 
 type OrderService struct {
-	Db                *db.PostgresDB
-	Cache             *cache.RedisClient
-	RMQ               *messaging.Publisher
-	ProductServiceURL string
-	HttpClient        *http.Client
+	Db       *db.PostgresDB
+	Cache    cache.Cache
+	RMQ      *messaging.Publisher
+	Products *product.Client
+
+	opts     Options
+	publish  *workerpool.Pool[outboundEvent]
+	cache    *workerpool.Pool[func(context.Context)]
+	products *productLookup
+	watchers *orderWatchers
+	bus      pubsub.Bus
+	stopBus  context.CancelFunc
+	stopping chan struct{}
+	stopOnce sync.Once
+
+	stopOutbox chan struct{}
+	outboxDone chan struct{}
+}
+
+// Options holds the OrderService tunables.
+type Options struct {
+	PublishPool workerpool.Config
+	CachePool   workerpool.Config
+	Consumer    messaging.ConsumerConfig
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
 
-	rmqWorkerPool chan map[string]interface{}
-	cacheWorker   chan int
-	wg            sync.WaitGroup
+	ProductCacheTTL time.Duration
+	OrdersCacheTTL  time.Duration
+
+	ProductL1TTL      time.Duration
+	ProductL1StaleTTL time.Duration
+	ProductL1MaxItems int
+
+	// EventBus carries order events to the streams of every replica; nil
+	// reaches this replica only.
+	EventBus pubsub.Bus
+	// EventHeartbeat is how often idle event streams are pinged.
+	EventHeartbeat time.Duration
 }
 
-const (
-	RMQWorkerCount    = 100
-	CacheWorkerBatch  = 1000 * time.Millisecond
-	CacheWorkerBuffer = 1000
-)
+func DefaultOptions() Options {
+	return Options{
+		PublishPool:        workerpool.Config{Workers: 100, QueueSize: 1000, Policy: workerpool.PolicySpill, BlockTimeout: 100 * time.Millisecond},
+		CachePool:          workerpool.Config{Workers: 4, QueueSize: 1000, Policy: workerpool.PolicyShed, BlockTimeout: 50 * time.Millisecond},
+		Consumer:           messaging.DefaultConsumerConfig(),
+		OutboxPollInterval: time.Second,
+		OutboxBatchSize:    100,
+		ProductCacheTTL:    300 * time.Second,
+		OrdersCacheTTL:     600 * time.Second,
+		ProductL1TTL:       5 * time.Second,
+		ProductL1StaleTTL:  60 * time.Second,
+		ProductL1MaxItems:  10000,
+		EventHeartbeat:     15 * time.Second,
+	}
+}
 
-func NewOrderService(pg *db.PostgresDB, r *cache.RedisClient, rmq *messaging.Publisher, productURL string) *OrderService {
+func NewOrderService(pg *db.PostgresDB, c cache.Cache, rmq *messaging.Publisher, products *product.Client, opts Options) (*OrderService, error) {
 	s := &OrderService{
-		Db:                pg,
-		Cache:             r,
-		RMQ:               rmq,
-		ProductServiceURL: productURL,
-		HttpClient: &http.Client{
-			Timeout: 2 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        200,
-				MaxIdleConnsPerHost: 200,
-			},
-		},
-		rmqWorkerPool: make(chan map[string]interface{}, 1000),
-		cacheWorker:   make(chan int, CacheWorkerBuffer),
+		Db:         pg,
+		Cache:      c,
+		RMQ:        rmq,
+		Products:   products,
+		opts:       opts,
+		watchers:   newOrderWatchers(),
+		stopping:   make(chan struct{}),
+		stopOutbox: make(chan struct{}),
+		outboxDone: make(chan struct{}),
 	}
 
-	for i := 0; i < RMQWorkerCount; i++ {
-		s.wg.Add(1)
-		go s.rmqWorker()
+	var err error
+	opts.PublishPool.Name = "publish"
+	if s.publish, err = workerpool.New(opts.PublishPool, s.publishEvent, s.spillToOutbox); err != nil {
+		return nil, err
 	}
+	opts.CachePool.Name = "cache"
+	if s.cache, err = workerpool.New(opts.CachePool, runTask, nil); err != nil {
+		return nil, err
+	}
+
+	s.products = newProductLookup(s.loadProduct, opts.ProductL1TTL, opts.ProductL1StaleTTL, opts.ProductL1MaxItems)
+
+	s.bus = opts.EventBus
+	if s.bus == nil {
+		s.bus = pubsub.NewLocalBus()
+	}
+	var busCtx context.Context
+	busCtx, s.stopBus = context.WithCancel(context.Background())
+	if err := s.bus.Subscribe(busCtx, orderEventsChannel, s.receiveEvent, s.watchers.resync); err != nil {
+		s.stopBus()
+		return nil, fmt.Errorf("subscribe to order events: %w", err)
+	}
+
+	go s.outboxRelay()
+	return s, nil
+}
 
-	go s.cacheWorkerLoop()
+// RegisterMetrics exposes the worker queue depths. Call it once per process.
+func (s *OrderService) RegisterMetrics() {
+	s.publish.RegisterMetrics()
+	s.cache.RegisterMetrics()
+}
 
-	return s
+// outboundEvent is an event waiting to be published.
+type outboundEvent struct {
+	RoutingKey string
+	Payload    map[string]interface{}
 }
 
-func (s *OrderService) rmqWorker() {
-	defer s.wg.Done()
-	for event := range s.rmqWorkerPool {
-		if err := s.RMQ.Publish("order.created", event); err != nil {
-			log.Printf("[RequestID: %v] FAILED to publish order.created: %v", event["requestId"], err)
+func (s *OrderService) publishEvent(ctx context.Context, ev outboundEvent) {
+	if err := s.RMQ.Publish(ctx, ev.RoutingKey, ev.Payload); err != nil {
+		logging.FromContext(ctx).Error("FAILED to publish event, moving it to the outbox", "routing_key", ev.RoutingKey, "error", err)
+		if err := s.spillToOutbox(ctx, ev); err != nil {
+			logging.FromContext(ctx).Error("Event LOST", "routing_key", ev.RoutingKey, "error", err)
 		}
 	}
 }
 
-// batch cache refresher
-func (s *OrderService) cacheWorkerLoop() {
-	productSet := map[int]struct{}{}
-	ticker := time.NewTicker(CacheWorkerBatch)
-	defer ticker.Stop()
+// publishCommitted queues the event of a change that is already committed.
+// Such events must not be shed, so one the pool rejects goes to the outbox
+// whatever its overflow policy.
+func (s *OrderService) publishCommitted(ctx context.Context, ev outboundEvent) {
+	err := s.publish.Submit(ctx, ev)
+	if err == nil {
+		return
+	}
+	logging.FromContext(ctx).Warn("Publish queue FULL, moving event to the outbox", "routing_key", ev.RoutingKey, "error", err)
+	if err := s.spillToOutbox(ctx, ev); err != nil {
+		logging.FromContext(ctx).Error("Event LOST", "routing_key", ev.RoutingKey, "error", err)
+	}
+}
 
+// spillToOutbox persists an event that could not be queued or published;
+// outboxRelay publishes it later.
+func (s *OrderService) spillToOutbox(ctx context.Context, ev outboundEvent) error {
+	payload, err := json.Marshal(ev.Payload)
+	if err != nil {
+		return err
+	}
+	return s.Db.InsertOutbox(ctx, ev.RoutingKey, payload)
+}
+
+func (s *OrderService) outboxRelay() {
+	defer close(s.outboxDone)
+	ticker := time.NewTicker(s.opts.OutboxPollInterval)
+	defer ticker.Stop()
 	for {
 		select {
-		case pid, ok := <-s.cacheWorker:
-			if !ok {
-				// flush remaining products before exit
-				for p := range productSet {
-					_ = s.refreshProductOrdersCache(p)
-				}
-				return
-			}
-			productSet[pid] = struct{}{}
+		case <-s.stopOutbox:
+			return
 		case <-ticker.C:
-			for p := range productSet {
-				_ = s.refreshProductOrdersCache(p)
+			s.drainOutbox()
+		}
+	}
+}
+
+func (s *OrderService) drainOutbox() {
+	ctx := context.Background()
+	for {
+		n, err := s.Db.DrainOutbox(ctx, s.opts.OutboxBatchSize, func(m db.OutboxMessage) error {
+			var payload map[string]interface{}
+			if err := json.Unmarshal(m.Payload, &payload); err != nil {
+				return err
 			}
-			productSet = map[int]struct{}{}
+			return s.RMQ.Publish(ctx, m.RoutingKey, payload)
+		})
+		if err != nil {
+			slog.Warn("Outbox drain INCOMPLETE", "published", n, "error", err)
+			return
+		}
+		if n > 0 {
+			slog.Info("Outbox events PUBLISHED", "count", n)
+		}
+		if n < s.opts.OutboxBatchSize {
+			return
+		}
+	}
+}
+
+func runTask(ctx context.Context, task func(context.Context)) {
+	task(ctx)
+}
+
+func (s *OrderService) applyOrderToCache(ctx context.Context, order *domain.Order) {
+	for _, key := range orderListKeys(order) {
+		if err := s.Cache.HashSet(ctx, key, strconv.Itoa(order.ID), cachedOrderField(order), s.opts.OrdersCacheTTL); err != nil {
+			logging.FromContext(ctx).Error("FAILED to update cached order, dropping cached list", "order_id", order.ID, "key", key, "error", err)
+			_ = s.Cache.HashDelete(ctx, key)
+		}
+	}
+}
+
+// enqueueCacheUpdate applies order to the cached per-product and
+// per-customer lists in the background. A shed update only costs a cache
+// miss, since the lists are rebuilt from Postgres when they expire.
+func (s *OrderService) enqueueCacheUpdate(ctx context.Context, order *domain.Order) {
+	err := s.cache.Submit(ctx, func(ctx context.Context) { s.applyOrderToCache(ctx, order) })
+	if err != nil {
+		logging.FromContext(ctx).Warn("Cache update SHED, dropping cached lists", "order_id", order.ID, "error", err)
+		for _, key := range orderListKeys(order) {
+			_ = s.Cache.HashDelete(ctx, key)
 		}
 	}
 }
 
-type productResponse struct {
-	ID    int     `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Qty   int     `json:"qty"`
+// orderListKeys returns the cached lists order belongs to.
+func orderListKeys(order *domain.Order) []string {
+	keys := []string{ordersCacheKey(order.TenantID, order.ProductID)}
+	if order.CustomerID != "" {
+		keys = append(keys, customerOrdersCacheKey(order.TenantID, order.CustomerID))
+	}
+	return keys
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, productID, quantity int) (*domain.Order, error) {
+// CreateOrder places an order for customerID, which is empty when the
+// request was not authenticated.
+func (s *OrderService) CreateOrder(ctx context.Context, customerID string, productID, quantity int) (*domain.Order, error) {
 	requestID := middleware.GetRequestID(ctx)
 
-	product, err := s.fetchProduct(productID, requestID)
+	// Nothing is written when the publish queue is already full and the
+	// policy is to shed, so the client can retry safely. The queue can still
+	// fill up before the event is submitted; publishCommitted then spills it.
+	if s.publish.Policy() == workerpool.PolicyShed && s.publish.Saturated() {
+		return nil, ErrOverloaded
+	}
+
+	prod, err := s.fetchProduct(ctx, productID, requestID)
 	if err != nil {
 		return nil, err
 	}
 
 	order := &domain.Order{
 		ProductID:  productID,
-		TotalPrice: float64(quantity) * product.Price,
-		Status:     "waiting",
+		CustomerID: customerID,
+		TotalPrice: float64(quantity) * prod.Price,
+		Status:     domain.OrderStatusWaiting,
 		CreatedAt:  time.Now(),
 	}
 
-	if err := s.Db.CreateOrder(order); err != nil {
-		log.Printf("[RequestID: %s] FAILED to create order: %v", requestID, err)
+	created, err := s.Db.CreateOrder(ctx, order)
+	if err != nil {
+		logging.FromContext(ctx).Error("FAILED to create order", "error", err)
 		return nil, err
 	}
 
-	select {
-	case s.cacheWorker <- productID:
-	default:
-		go func(pid int) { _ = s.refreshProductOrdersCache(pid) }(productID)
-	}
+	s.enqueueCacheUpdate(ctx, order)
+	s.broadcast(ctx, created)
 
 	event := map[string]interface{}{
-		"orderId":   order.ID,
-		"productId": order.ProductID,
-		"quantity":  quantity,
-		"status":    order.Status,
-		"createdAt": order.CreatedAt,
-		"requestId": requestID,
+		"orderId":    order.ID,
+		"productId":  order.ProductID,
+		"customerId": order.CustomerID,
+		"tenantId":   order.TenantID,
+		"quantity":   quantity,
+		"status":     order.Status,
+		"createdAt":  order.CreatedAt,
+		"requestId":  requestID,
 	}
 
-	select {
-	case s.rmqWorkerPool <- event:
-	default:
-		go func() { _ = s.RMQ.Publish("order.created", event) }()
-	}
+	s.publishCommitted(ctx, outboundEvent{RoutingKey: OrderCreatedKey + "." + order.TenantID, Payload: event})
 
-	log.Printf("[RequestID: %s] Order %d created successfully", requestID, order.ID)
+	logging.FromContext(ctx).Info("Order CREATED", "order_id", order.ID)
 	return order, nil
 }
 
-func (s *OrderService) fetchProduct(productID int, requestID string) (*productResponse, error) {
-	cacheKey := fmt.Sprintf("product:%d", productID)
-	if data, err := s.Cache.Get(cacheKey); err == nil && data != nil {
-		var prod productResponse
-		if err := json.Unmarshal(data, &prod); err == nil {
-			return &prod, nil
+// fetchProduct resolves a product through the in-process L1 cache, which
+// coalesces concurrent misses into a single loadProduct call.
+func (s *OrderService) fetchProduct(ctx context.Context, productID int, requestID string) (*domain.Product, error) {
+	return s.products.Get(ctx, productID, requestID)
+}
+
+// loadProduct resolves a product from the Redis cache, then the local
+// catalog replica, and only calls the product-service for products the
+// replica has not seen yet.
+func (s *OrderService) loadProduct(ctx context.Context, productID int, requestID string) (*domain.Product, error) {
+	cacheKey := productCacheKey(productID)
+	gen := s.products.generation(productID)
+	data, err := s.Cache.Get(ctx, cacheKey)
+	metrics.CacheResult(metrics.KeyspaceProduct, data != nil, err)
+	if err == nil && data != nil {
+		if prod, err := decodeCachedProduct(data); err == nil {
+			return prod, nil
 		}
 	}
 
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/products/%d", s.ProductServiceURL, productID), nil)
-	req.Header.Set("X-Request-ID", requestID)
-	res, err := s.HttpClient.Do(req)
-	if err != nil || res.StatusCode != http.StatusOK {
-		log.Printf("[RequestID: %s] FAILED to fetch product %d: %v", requestID, productID, err)
-		return nil, fmt.Errorf("product not found")
+	prod, err := s.Db.GetProductByID(ctx, productID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("FAILED to read product from catalog", "product_id", productID, "error", err)
 	}
-	defer res.Body.Close()
 
-	var prod productResponse
-	if err := json.NewDecoder(res.Body).Decode(&prod); err != nil {
-		return nil, fmt.Errorf("failed to decode product")
+	if prod == nil {
+		prod, err = s.Products.GetProduct(ctx, productID, requestID)
+		if err != nil {
+			logging.FromContext(ctx).Error("FAILED to fetch product", "product_id", productID, "error", err)
+			return nil, err
+		}
+		// The backfill carries no version, so the next product event for
+		// it wins and a tombstone keeps it out.
+		backfill := *prod
+		backfill.UpdatedAt = time.Time{}
+		if err := s.Db.UpsertProduct(ctx, &backfill); err != nil {
+			logging.FromContext(ctx).Warn("FAILED to backfill product", "product_id", productID, "error", err)
+		}
 	}
 
-	go func() { _ = s.Cache.Set(cacheKey, prod, 300) }()
-	return &prod, nil
+	_ = s.cache.Submit(ctx, func(ctx context.Context) {
+		// A product event invalidates before deleting the key, so checking
+		// again after the write catches one that ran in between.
+		if s.products.generation(productID) != gen {
+			return
+		}
+		_ = s.Cache.Set(ctx, cacheKey, newCachedProduct(prod), s.opts.ProductCacheTTL)
+		if s.products.generation(productID) != gen {
+			_ = s.Cache.Delete(ctx, cacheKey)
+		}
+	})
+	return prod, nil
 }
 
 func (s *OrderService) ListenOrderUpdated() error {
-	return s.RMQ.Subscribe("order.updated", func(body []byte) {
-		go s.handleOrderUpdated(body)
-	})
+	// "#" also matches the untenanted key of older publishers
+	return s.RMQ.Subscribe(OrderUpdatedKey+".#", s.handleOrderUpdated, s.opts.Consumer)
 }
 
-func (s *OrderService) handleOrderUpdated(body []byte) {
+// handleOrderUpdated applies a status change within the tenant named by the
+// event, or DefaultTenant for events without one. Malformed messages and
+// unknown orders are permanent failures; anything else is retried.
+func (s *OrderService) handleOrderUpdated(ctx context.Context, body []byte) error {
 	var msg struct {
 		OrderID   int    `json:"orderId"`
 		ProductID int    `json:"productId"`
 		Status    string `json:"status"`
 		UpdatedAt string `json:"updatedAt"`
 		RequestID string `json:"requestId"`
+		TenantID  string `json:"tenantId"`
 	}
 
 	if err := json.Unmarshal(body, &msg); err != nil {
-		log.Println("FAILED to decode order.updated:", err)
-		return
+		return messaging.Permanent(fmt.Errorf("decode order.updated: %w", err))
+	}
+	if msg.OrderID <= 0 || msg.Status == "" {
+		return messaging.Permanent(fmt.Errorf("order.updated without orderId or status"))
+	}
+	if msg.TenantID == "" {
+		msg.TenantID = db.DefaultTenant
+	}
+	if err := tenant.Validate(msg.TenantID); err != nil {
+		return messaging.Permanent(err)
 	}
 
-	reqID := msg.RequestID
-	if reqID == "" {
-		reqID = "no-request-id"
+	ctx = tenant.WithTenant(ctx, msg.TenantID)
+	ctx = logging.With(ctx, "request_id", msg.RequestID, "order_id", msg.OrderID, "tenant", msg.TenantID)
+
+	order, changed, err := s.Db.UpdateOrderStatus(ctx, msg.OrderID, msg.Status, domain.WebhookOrderUpdated)
+	if errors.Is(err, sql.ErrNoRows) {
+		// cancelled orders keep their status
+		if _, err := s.GetOrder(ctx, msg.OrderID); errors.Is(err, ErrOrderNotFound) {
+			return messaging.Permanent(fmt.Errorf("order %d not found", msg.OrderID))
+		} else if err != nil {
+			return fmt.Errorf("get order %d: %w", msg.OrderID, err)
+		}
+		logging.FromContext(ctx).Info("Order update IGNORED, order is cancelled", "status", msg.Status)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("update order %d: %w", msg.OrderID, err)
 	}
 
-	if err := s.Db.UpdateOrderStatus(msg.OrderID, msg.Status); err != nil {
-		log.Printf("[RequestID: %s] FAILED to update order %d: %v", reqID, msg.OrderID, err)
-		return
+	s.enqueueCacheUpdate(ctx, order)
+	s.broadcast(ctx, changed)
+
+	logging.FromContext(ctx).Info("Order UPDATED", "status", msg.Status)
+	return nil
+}
+
+// GetOrder returns an order of the tenant of ctx, or ErrOrderNotFound.
+func (s *OrderService) GetOrder(ctx context.Context, orderID int) (*domain.Order, error) {
+	order, err := s.Db.GetOrderByID(ctx, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
 	}
+	return order, err
+}
 
-	select {
-	case s.cacheWorker <- msg.ProductID:
-	default:
-		go func(pid int) { _ = s.refreshProductOrdersCache(pid) }(msg.ProductID)
+// CancelOrder cancels an order of the tenant of ctx and publishes
+// order.cancelled. Callers check that the caller owns the order first.
+func (s *OrderService) CancelOrder(ctx context.Context, orderID int) (*domain.Order, error) {
+	order, cancelled, err := s.Db.UpdateOrderStatus(ctx, orderID, domain.OrderStatusCancelled, domain.WebhookOrderCancelled)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.GetOrder(ctx, orderID); err != nil {
+			return nil, err
+		}
+		return nil, ErrOrderCancelled
 	}
+	if err != nil {
+		logging.FromContext(ctx).Error("FAILED to cancel order", "order_id", orderID, "error", err)
+		return nil, err
+	}
+
+	s.enqueueCacheUpdate(ctx, order)
+	s.broadcast(ctx, cancelled)
 
-	log.Printf("[RequestID: %s] Order %d updated to '%s'", reqID, msg.OrderID, msg.Status)
+	event := map[string]interface{}{
+		"orderId":    order.ID,
+		"productId":  order.ProductID,
+		"customerId": order.CustomerID,
+		"tenantId":   order.TenantID,
+		"status":     order.Status,
+		"requestId":  middleware.GetRequestID(ctx),
+	}
+	s.publishCommitted(ctx, outboundEvent{RoutingKey: OrderCancelledKey + "." + order.TenantID, Payload: event})
+
+	logging.FromContext(ctx).Info("Order CANCELLED", "order_id", order.ID)
+	return order, nil
 }
 
-func (s *OrderService) refreshProductOrdersCache(productID int) error {
-	orders, err := s.Db.GetOrdersByProductID(productID)
+// GetOrdersByProductID lists the orders of a product within the tenant of ctx.
+func (s *OrderService) GetOrdersByProductID(ctx context.Context, productID int) ([]*domain.Order, error) {
+	tenantID, err := tenant.FromContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return s.cachedOrders(ctx, ordersCacheKey(tenantID, productID), metrics.KeyspaceOrdersProduct, func() ([]*domain.Order, error) {
+		return s.Db.GetOrdersByProductID(ctx, productID)
+	})
+}
+
+// GetOrdersByCustomerID lists the orders of a customer within the tenant of ctx.
+func (s *OrderService) GetOrdersByCustomerID(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	key := fmt.Sprintf("orders:product:%d", productID)
-	return s.Cache.Set(key, orders, 600)
+	return s.cachedOrders(ctx, customerOrdersCacheKey(tenantID, customerID), metrics.KeyspaceOrdersCustomer, func() ([]*domain.Order, error) {
+		return s.Db.GetOrdersByCustomerID(ctx, customerID)
+	})
 }
 
-func (s *OrderService) GetOrdersByProductID(productID int) ([]*domain.Order, error) {
-	cacheKey := fmt.Sprintf("orders:product:%d", productID)
-	if data, err := s.Cache.Get(cacheKey); err == nil && data != nil {
-		var orders []*domain.Order
-		if err := json.Unmarshal(data, &orders); err == nil {
+// cachedOrders reads an order list from its versioned cache hash, falling
+// back to load and repopulating the cache on a miss.
+func (s *OrderService) cachedOrders(ctx context.Context, cacheKey, keyspace string, load func() ([]*domain.Order, error)) ([]*domain.Order, error) {
+	fields, ok, err := s.Cache.HashGetAll(ctx, cacheKey)
+	metrics.CacheResult(keyspace, ok, err)
+	if err == nil && ok {
+		if orders, err := decodeOrders(fields); err == nil {
 			return orders, nil
 		}
 	}
 
-	orders, err := s.Db.GetOrdersByProductID(productID)
+	orders, err := load()
 	if err != nil {
 		return nil, err
 	}
 
-	_ = s.Cache.Set(cacheKey, orders, 600)
+	entries := make(map[string]cache.HashField, len(orders))
+	for _, o := range orders {
+		entries[strconv.Itoa(o.ID)] = cachedOrderField(o)
+	}
+	_ = s.Cache.HashReplace(ctx, cacheKey, entries, s.opts.OrdersCacheTTL)
+	return orders, nil
+}
+
+func decodeOrders(fields map[string][]byte) ([]*domain.Order, error) {
+	orders := make([]*domain.Order, 0, len(fields))
+	for _, data := range fields {
+		o, err := decodeCachedOrder(data)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
 	return orders, nil
 }
 
+// ordersCacheKey braces the tenant and product ID so every version of the
+// list hashes to the same Redis Cluster slot.
+func ordersCacheKey(tenantID string, productID int) string {
+	return fmt.Sprintf("orders:product:{%s:%d}", tenantID, productID)
+}
+
+func customerOrdersCacheKey(tenantID, customerID string) string {
+	return fmt.Sprintf("orders:customer:{%s:%s}", tenantID, customerID)
+}
+
+// Shutdown ends the event streams, stops the worker pools, letting each
+// drain its queue until ctx is done, then stops the outbox relay and the
+// event subscription. Callers should stop HTTP traffic and consumers first.
+func (s *OrderService) Shutdown(ctx context.Context) error {
+	s.StopWatching()
+	defer s.stopBus()
+
+	var errs []error
+	for _, closer := range []func(context.Context) error{s.publish.Close, s.cache.Close} {
+		if err := closer(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	select {
+	case <-s.stopOutbox:
+	default:
+		close(s.stopOutbox)
+	}
+	select {
+	case <-s.outboxDone:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("timed out stopping outbox relay: %w", ctx.Err()))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.Info("Order service queues FLUSHED")
+	return nil
+}
+
 func (s *OrderService) Close() {
-	close(s.rmqWorkerPool)
-	close(s.cacheWorker)
-	s.wg.Wait()
+	_ = s.Shutdown(context.Background())
 }
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	ProductL1TTL       = 5 * time.Second
+	ProductL1StaleTTL  = 60 * time.Second
+	ProductL1MaxItems  = 10000
+	productLoadTimeout = 5 * time.Second
+)
+
+type productEntry struct {
+	productID  int
+	product    *domain.Product
+	freshUntil time.Time
+	staleUntil time.Time
+}
+
+// productLookup is an in-process L1 cache in front of Redis, holding up to
+// maxItems products and evicting the least recently used. Concurrent misses
+// for the same product share one load, and expired entries keep being
+// served while a single background refresh runs. Each product has a
+// generation that Invalidate bumps, so a load that started before an
+// invalidation does not store what it read.
+type productLookup struct {
+	load     func(ctx context.Context, productID int, requestID string) (*domain.Product, error)
+	ttl      time.Duration
+	staleTTL time.Duration
+	maxItems int
+
+	mutex   sync.Mutex
+	ll      *list.List
+	entries map[int]*list.Element
+	// generations holds the products invalidated since generationFloor was
+	// last raised; every other product is at generationFloor. Raising the
+	// floor to the newest generation bounds the map without moving any
+	// product back to a generation a load may have seen.
+	generations     map[int]uint64
+	generationFloor uint64
+	lastGeneration  uint64
+	group           singleflight.Group
+	now             func() time.Time
+}
+
+func newProductLookup(load func(ctx context.Context, productID int, requestID string) (*domain.Product, error), ttl, staleTTL time.Duration, maxItems int) *productLookup {
+	return &productLookup{
+		load:        load,
+		ttl:         ttl,
+		staleTTL:    staleTTL,
+		maxItems:    maxItems,
+		ll:          list.New(),
+		entries:     make(map[int]*list.Element),
+		generations: make(map[int]uint64),
+		now:         time.Now,
+	}
+}
+
+func (l *productLookup) Get(ctx context.Context, productID int, requestID string) (*domain.Product, error) {
+	now := l.now()
+
+	l.mutex.Lock()
+	var entry productEntry
+	el, ok := l.entries[productID]
+	if ok {
+		l.ll.MoveToFront(el)
+		entry = *el.Value.(*productEntry)
+	}
+	l.mutex.Unlock()
+
+	if ok && now.Before(entry.freshUntil) {
+		metrics.CacheResult(metrics.KeyspaceProductL1, true, nil)
+		return entry.product, nil
+	}
+	if ok && now.Before(entry.staleUntil) {
+		metrics.CacheResult(metrics.KeyspaceProductL1, true, nil)
+		l.group.DoChan(strconv.Itoa(productID), func() (interface{}, error) {
+			return l.fill(context.WithoutCancel(ctx), productID, requestID)
+		})
+		return entry.product, nil
+	}
+
+	metrics.CacheResult(metrics.KeyspaceProductL1, false, nil)
+	ch := l.group.DoChan(strconv.Itoa(productID), func() (interface{}, error) {
+		// Detach from the first caller so its cancellation doesn't fail
+		// everyone else waiting on the same load.
+		return l.fill(context.WithoutCancel(ctx), productID, requestID)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*domain.Product), nil
+	}
+}
+
+func (l *productLookup) fill(ctx context.Context, productID int, requestID string) (*domain.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, productLoadTimeout)
+	defer cancel()
+
+	gen := l.generation(productID)
+	prod, err := l.load(ctx, productID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := l.now()
+	entry := &productEntry{
+		productID:  productID,
+		product:    prod,
+		freshUntil: now.Add(l.ttl),
+		staleUntil: now.Add(l.ttl + l.staleTTL),
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.generationLocked(productID) != gen {
+		return prod, nil
+	}
+	if el, ok := l.entries[productID]; ok {
+		el.Value = entry
+		l.ll.MoveToFront(el)
+		return prod, nil
+	}
+	if l.ll.Len() >= l.maxItems {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.entries, oldest.Value.(*productEntry).productID)
+	}
+	l.entries[productID] = l.ll.PushFront(entry)
+	return prod, nil
+}
+
+// Invalidate drops a product from the L1 cache and bumps its generation.
+// Callers that read the product before the bump get the old value, but new
+// callers start a fresh load instead of joining one already in flight.
+func (l *productLookup) Invalidate(productID int) {
+	l.mutex.Lock()
+	if el, ok := l.entries[productID]; ok {
+		l.ll.Remove(el)
+		delete(l.entries, productID)
+	}
+	l.lastGeneration++
+	if len(l.generations) >= l.maxItems {
+		l.generations = make(map[int]uint64)
+		l.generationFloor = l.lastGeneration
+	} else {
+		l.generations[productID] = l.lastGeneration
+	}
+	l.mutex.Unlock()
+	l.group.Forget(strconv.Itoa(productID))
+}
+
+func (l *productLookup) generation(productID int) uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.generationLocked(productID)
+}
+
+func (l *productLookup) generationLocked(productID int) uint64 {
+	if gen, ok := l.generations[productID]; ok {
+		return gen
+	}
+	return l.generationFloor
+}
@@ -0,0 +1,198 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/service"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+// priceLoader returns the current price and counts its calls. While gate is
+// set, loads block until it is closed.
+type priceLoader struct {
+	price atomic.Int64
+	calls atomic.Int32
+	gate  chan struct{}
+}
+
+func (p *priceLoader) load(ctx context.Context, productID int, _ string) (*domain.Product, error) {
+	p.calls.Add(1)
+	price := p.price.Load()
+	if p.gate != nil {
+		select {
+		case <-p.gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &domain.Product{ID: productID, Price: float64(price)}, nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestProductLookup_CoalescesMisses(t *testing.T) {
+	loader := &priceLoader{gate: make(chan struct{})}
+	loader.price.Store(10)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := service.NewProductLookup(loader.load, time.Second, time.Minute, service.ProductL1MaxItems, clock.Now)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prod, err := l.Get(context.Background(), 1, "")
+			if err != nil || prod.Price != 10 {
+				t.Errorf("Get = %v, %v", prod, err)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return loader.calls.Load() == 1 })
+	time.Sleep(10 * time.Millisecond)
+	close(loader.gate)
+	wg.Wait()
+
+	if n := loader.calls.Load(); n != 1 {
+		t.Errorf("Expected 1 load, got %d", n)
+	}
+}
+
+func TestProductLookup_ServesStaleWhileRevalidating(t *testing.T) {
+	loader := &priceLoader{}
+	loader.price.Store(10)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := service.NewProductLookup(loader.load, time.Second, time.Minute, service.ProductL1MaxItems, clock.Now)
+
+	if _, err := l.Get(context.Background(), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	loader.price.Store(20)
+	loader.gate = make(chan struct{})
+	clock.Advance(2 * time.Second)
+
+	prod, err := l.Get(context.Background(), 1, "")
+	if err != nil || prod.Price != 10 {
+		t.Fatalf("Expected the stale price while refreshing, got %v, %v", prod, err)
+	}
+	close(loader.gate)
+	waitFor(t, func() bool {
+		prod, _ := l.Get(context.Background(), 1, "")
+		return prod.Price == 20
+	})
+
+	clock.Advance(2 * time.Minute)
+	loader.price.Store(30)
+	prod, err = l.Get(context.Background(), 1, "")
+	if err != nil || prod.Price != 30 {
+		t.Errorf("Expected a blocking load past the stale window, got %v, %v", prod, err)
+	}
+}
+
+func TestProductLookup_InvalidateDuringLoad(t *testing.T) {
+	loader := &priceLoader{gate: make(chan struct{})}
+	loader.price.Store(10)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := service.NewProductLookup(loader.load, time.Minute, time.Minute, service.ProductL1MaxItems, clock.Now)
+
+	done := make(chan *domain.Product)
+	go func() {
+		prod, _ := l.Get(context.Background(), 1, "")
+		done <- prod
+	}()
+	waitFor(t, func() bool { return loader.calls.Load() == 1 })
+
+	loader.price.Store(20)
+	l.Invalidate(1)
+	close(loader.gate)
+	if prod := <-done; prod.Price != 10 {
+		t.Fatalf("in-flight caller got %v, want the price it loaded", prod.Price)
+	}
+
+	prod, err := l.Get(context.Background(), 1, "")
+	if err != nil || prod.Price != 20 {
+		t.Errorf("Expected the load from before Invalidate to be dropped, got %v, %v", prod, err)
+	}
+}
+
+func TestProductLookup_EvictsLeastRecentlyUsed(t *testing.T) {
+	loader := &priceLoader{}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := service.NewProductLookup(loader.load, time.Minute, time.Minute, 2, clock.Now)
+
+	for _, id := range []int{1, 2, 1, 3} {
+		if _, err := l.Get(context.Background(), id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := loader.calls.Load(); n != 3 {
+		t.Fatalf("Expected 3 loads, got %d", n)
+	}
+
+	l.Get(context.Background(), 1, "")
+	if n := loader.calls.Load(); n != 3 {
+		t.Errorf("Expected the recently used product to stay cached, got %d loads", n)
+	}
+	l.Get(context.Background(), 2, "")
+	if n := loader.calls.Load(); n != 4 {
+		t.Errorf("Expected the least recently used product to be evicted, got %d loads", n)
+	}
+}
+
+func TestProductLookup_BoundsGenerations(t *testing.T) {
+	loader := &priceLoader{gate: make(chan struct{})}
+	loader.price.Store(10)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := service.NewProductLookup(loader.load, time.Minute, time.Minute, 2, clock.Now)
+
+	done := make(chan *domain.Product)
+	go func() {
+		prod, _ := l.Get(context.Background(), 1, "")
+		done <- prod
+	}()
+	waitFor(t, func() bool { return loader.calls.Load() == 1 })
+
+	for id := 1; id <= 10; id++ {
+		l.Invalidate(id)
+	}
+	if n := l.Generations(); n > 2 {
+		t.Errorf("Expected at most 2 generations, got %d", n)
+	}
+
+	loader.price.Store(20)
+	close(loader.gate)
+	<-done
+	prod, err := l.Get(context.Background(), 1, "")
+	if err != nil || prod.Price != 20 {
+		t.Errorf("Expected the load from before Invalidate to be dropped, got %v, %v", prod, err)
+	}
+}
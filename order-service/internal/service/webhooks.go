@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/logging"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/webhook"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// CreateWebhook subscribes an endpoint of the tenant of ctx, generating its
+// signing secret unless the DTO names one.
+func (s *OrderService) CreateWebhook(ctx context.Context, req domain.CreateWebhookDTO) (*domain.Webhook, error) {
+	w := &domain.Webhook{URL: req.URL, Secret: req.Secret, Events: req.Events}
+	if w.Events == nil {
+		w.Events = domain.WebhookEvents
+	}
+	if w.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			return nil, err
+		}
+		w.Secret = secret
+	}
+	if err := s.Db.CreateWebhook(ctx, w); err != nil {
+		logging.FromContext(ctx).Error("FAILED to create webhook", "error", err)
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Webhook CREATED", "webhook_id", w.ID)
+	return w, nil
+}
+
+// ListWebhooks returns the webhooks of the tenant of ctx.
+func (s *OrderService) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	return s.Db.ListWebhooks(ctx)
+}
+
+// GetWebhook returns a webhook of the tenant of ctx, or ErrWebhookNotFound.
+func (s *OrderService) GetWebhook(ctx context.Context, id int) (*domain.Webhook, error) {
+	w, err := s.Db.GetWebhook(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+// UpdateWebhook applies the fields set in req. Activating a webhook resets
+// its failure count, so a disabled webhook gets a fresh start.
+func (s *OrderService) UpdateWebhook(ctx context.Context, id int, req domain.UpdateWebhookDTO) (*domain.Webhook, error) {
+	w, err := s.Db.UpdateWebhook(ctx, id, func(w *domain.Webhook) {
+		if req.URL != nil {
+			w.URL = *req.URL
+		}
+		if req.Events != nil {
+			w.Events = req.Events
+		}
+		if req.Active != nil && *req.Active != w.Active {
+			w.Active = *req.Active
+			if w.Active {
+				w.ConsecutiveFailures = 0
+				w.DisabledAt = nil
+			} else {
+				now := time.Now()
+				w.DisabledAt = &now
+			}
+		}
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("FAILED to update webhook", "webhook_id", id, "error", err)
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Webhook UPDATED", "webhook_id", id, "active", w.Active)
+	return w, nil
+}
+
+// DeleteWebhook unsubscribes a webhook of the tenant of ctx and drops its
+// delivery log.
+func (s *OrderService) DeleteWebhook(ctx context.Context, id int) error {
+	err := s.Db.DeleteWebhook(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("FAILED to delete webhook", "webhook_id", id, "error", err)
+		return err
+	}
+	logging.FromContext(ctx).Info("Webhook DELETED", "webhook_id", id)
+	return nil
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook of the tenant
+// of ctx, newest first.
+func (s *OrderService) ListWebhookDeliveries(ctx context.Context, webhookID int, status string, beforeID int64, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.Db.ListWebhookDeliveries(ctx, webhookID, status, beforeID, limit)
+}
@@ -0,0 +1,40 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// ErrMissing is returned by repository calls made without a tenant in the
+// context.
+var ErrMissing = errors.New("no tenant in context")
+
+// ErrInvalid is returned for tenant IDs that are not safe to embed in cache
+// keys and routing keys.
+var ErrInvalid = errors.New("tenant ID must be 1-64 letters, digits, '-' or '_'")
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Validate checks that id can be used as a tenant ID.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+type ctxKey struct{}
+
+// WithTenant returns a copy of ctx scoped to tenant id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, or ErrMissing.
+func FromContext(ctx context.Context) (string, error) {
+	if id, ok := ctx.Value(ctxKey{}).(string); ok && id != "" {
+		return id, nil
+	}
+	return "", ErrMissing
+}
@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single invalid field in a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors collected while validating a request.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Add appends a field error.
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// HasErrors reports whether any field error was collected.
+func (e Errors) HasErrors() bool {
+	return len(e) > 0
+}
+
+// PositiveInt checks that value is greater than zero.
+func (e *Errors) PositiveInt(field string, value int) {
+	if value <= 0 {
+		e.Add(field, "must be a positive integer")
+	}
+}
+
+// IntRange checks that value is within [min, max].
+func (e *Errors) IntRange(field string, value, min, max int) {
+	if value < min || value > max {
+		e.Add(field, fmt.Sprintf("must be between %d and %d", min, max))
+	}
+}
+
+// HTTPURL checks that value is an absolute http or https URL.
+func (e *Errors) HTTPURL(field, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		e.Add(field, "must be an absolute http or https URL")
+	}
+}
+
+// ParsePositiveInt parses a path or query parameter as a positive integer.
+func ParsePositiveInt(field, raw string) (int, Errors) {
+	var errs Errors
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		errs.Add(field, "must be an integer")
+		return 0, errs
+	}
+	errs.PositiveInt(field, v)
+	if errs.HasErrors() {
+		return 0, errs
+	}
+	return v, nil
+}
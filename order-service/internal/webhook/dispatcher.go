@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/db"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errPrivateTarget is returned for endpoints resolving to loopback, private
+// or link-local addresses, which would let tenants probe our network.
+var errPrivateTarget = errors.New("webhook target resolves to a private address")
+
+// maxErrorLength caps the error stored with a failed attempt.
+const maxErrorLength = 500
+
+// Store persists the delivery queue.
+type Store interface {
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]db.ClaimedDelivery, error)
+	FinishWebhookDelivery(ctx context.Context, r db.DeliveryResult) (disabled bool, err error)
+}
+
+type Config struct {
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+	Timeout      time.Duration
+	// MaxAttempts is how often a delivery is tried before it fails for good.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DisableAfter consecutive failed attempts disable a webhook.
+	DisableAfter int
+	// AllowPrivateTargets permits loopback and private network endpoints.
+	AllowPrivateTargets bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:      10,
+		BatchSize:    100,
+		PollInterval: time.Second,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		DisableAfter: 20,
+	}
+}
+
+// Dispatcher delivers queued webhook events. Every replica may run one;
+// deliveries are leased so each is attempted by one replica at a time.
+type Dispatcher struct {
+	store Store
+	cfg   Config
+	http  *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func NewDispatcher(store Store, cfg Config) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateTargets {
+		dialer.Control = denyPrivate
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		store: store,
+		cfg:   cfg,
+		http: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, MaxIdleConnsPerHost: 2},
+			// a redirect is a failed delivery, not a new target
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start polls for due deliveries until Stop.
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-timer.C:
+		}
+
+		n, err := d.DispatchDue(d.ctx)
+		if err != nil {
+			slog.Error("FAILED to claim webhook deliveries", "error", err)
+		}
+		if n == d.cfg.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(d.cfg.PollInterval)
+		}
+	}
+}
+
+// Stop stops polling and waits for running attempts until ctx is done, then
+// abandons them; their leases expire and another poll retries them.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+	select {
+	case <-d.done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return fmt.Errorf("abandoned webhook deliveries: %w", ctx.Err())
+	}
+}
+
+// DispatchDue attempts up to BatchSize due deliveries, Workers at a time,
+// and returns how many it claimed.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	claimed, err := d.store.ClaimWebhookDeliveries(ctx, d.cfg.BatchSize, d.lease())
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.cfg.Workers)
+	for i := range claimed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(c *db.ClaimedDelivery) {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, c)
+		}(&claimed[i])
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// lease outlasts the slowest possible batch.
+func (d *Dispatcher) lease() time.Duration {
+	rounds := (d.cfg.BatchSize + d.cfg.Workers - 1) / d.cfg.Workers
+	return time.Duration(rounds+1) * d.cfg.Timeout
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, c *db.ClaimedDelivery) {
+	log := slog.With("delivery_id", c.ID, "webhook_id", c.WebhookID, "tenant", c.TenantID, "event", c.Event)
+
+	start := time.Now()
+	status, err := d.send(ctx, c)
+	metrics.WebhookDuration.Observe(time.Since(start).Seconds())
+	if ctx.Err() != nil {
+		// shutting down, the lease brings it back
+		return
+	}
+
+	result := db.DeliveryResult{
+		DeliveryID:     c.ID,
+		WebhookID:      c.WebhookID,
+		Succeeded:      err == nil,
+		ResponseStatus: status,
+		DisableAfter:   d.cfg.DisableAfter,
+	}
+	switch {
+	case err == nil:
+		metrics.WebhookDeliveries.WithLabelValues("succeeded").Inc()
+	case c.Attempts+1 >= d.cfg.MaxAttempts:
+		result.Error = truncate(err.Error())
+		result.GiveUp = true
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+		log.Warn("Webhook delivery FAILED, giving up", "attempts", c.Attempts+1, "error", err)
+	default:
+		result.Error = truncate(err.Error())
+		result.RetryIn = d.backoff(c.Attempts + 1)
+		metrics.WebhookDeliveries.WithLabelValues("retrying").Inc()
+		log.Info("Webhook delivery FAILED, retrying", "attempts", c.Attempts+1, "retry_in", result.RetryIn, "error", err)
+	}
+
+	disabled, err := d.store.FinishWebhookDelivery(ctx, result)
+	if err != nil {
+		log.Error("FAILED to record webhook delivery", "error", err)
+		return
+	}
+	if disabled {
+		log.Warn("Webhook DISABLED after repeated failures", "failures", d.cfg.DisableAfter)
+	}
+}
+
+// send posts the signed payload and returns the response status. Anything
+// but a 2xx response is an error.
+func (d *Dispatcher) send(ctx context.Context, c *db.ClaimedDelivery) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "webhook.Deliver", trace.SpanKindClient,
+		attribute.Int64("webhook.delivery_id", c.ID),
+		attribute.String("webhook.event", c.Event),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(c.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "order-service-webhooks/1")
+	req.Header.Set(HeaderID, strconv.FormatInt(c.ID, 10))
+	req.Header.Set(HeaderEvent, c.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(c.Secret, timestamp, c.Payload))
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the delay with every attempt up to MaxBackoff, keeping
+// half of it as jitter so retries of many deliveries spread out.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseBackoff << (attempt - 1)
+	if delay <= 0 || delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateTarget
+	}
+	return nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+	return s
+}
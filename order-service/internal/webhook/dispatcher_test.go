@@ -0,0 +1,135 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/domain"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/infra/db"
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/webhook"
+)
+
+type fakeStore struct {
+	mutex   sync.Mutex
+	pending []db.ClaimedDelivery
+	results []db.DeliveryResult
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]db.ClaimedDelivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := min(limit, len(s.pending))
+	claimed := s.pending[:n]
+	s.pending = s.pending[n:]
+	return claimed, nil
+}
+
+func (s *fakeStore) FinishWebhookDelivery(_ context.Context, r db.DeliveryResult) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results = append(s.results, r)
+	return false, nil
+}
+
+func delivery(url string, attempts int) db.ClaimedDelivery {
+	return db.ClaimedDelivery{
+		WebhookDelivery: domain.WebhookDelivery{
+			ID:        7,
+			WebhookID: 3,
+			Event:     domain.WebhookOrderUpdated,
+			Payload:   []byte(`{"event":"order.updated"}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func testConfig() webhook.Config {
+	cfg := webhook.DefaultConfig()
+	cfg.Timeout = time.Second
+	cfg.AllowPrivateTargets = true
+	return cfg
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if !webhook.Verify("whsec_test", ts, body, r.Header.Get(webhook.HeaderSignature)) {
+			t.Errorf("signature %q does not verify", r.Header.Get(webhook.HeaderSignature))
+		}
+		if r.Header.Get(webhook.HeaderEvent) != domain.WebhookOrderUpdated || r.Header.Get(webhook.HeaderID) != "7" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{pending: []db.ClaimedDelivery{delivery(srv.URL, 0)}}
+	n, err := webhook.NewDispatcher(store, testConfig()).DispatchDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 delivery, got %d, %v", n, err)
+	}
+	if len(store.results) != 1 || !store.results[0].Succeeded || store.results[0].ResponseStatus != http.StatusNoContent {
+		t.Fatalf("Expected a successful result, got %+v", store.results)
+	}
+}
+
+func TestDispatcher_RetriesThenGivesUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	store := &fakeStore{pending: []db.ClaimedDelivery{delivery(srv.URL, 0), delivery(srv.URL, cfg.MaxAttempts-1)}}
+	if _, err := webhook.NewDispatcher(store, cfg).DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", store.results)
+	}
+	var retried, gaveUp int
+	for _, r := range store.results {
+		if r.Succeeded || r.ResponseStatus != http.StatusBadGateway || r.DisableAfter != cfg.DisableAfter {
+			t.Errorf("unexpected result %+v", r)
+		}
+		if r.GiveUp {
+			gaveUp++
+		} else if r.RetryIn >= cfg.BaseBackoff/2 && r.RetryIn <= cfg.BaseBackoff {
+			retried++
+		}
+	}
+	if retried != 1 || gaveUp != 1 {
+		t.Fatalf("Expected one retry and one give up, got %+v", store.results)
+	}
+}
+
+func TestDispatcher_RefusesPrivateTargets(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.AllowPrivateTargets = false
+	store := &fakeStore{pending: []db.ClaimedDelivery{delivery(srv.URL, 0)}}
+	if _, err := webhook.NewDispatcher(store, cfg).DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if called {
+		t.Fatal("loopback endpoint was called")
+	}
+	if len(store.results) != 1 || store.results[0].Succeeded || !strings.Contains(store.results[0].Error, "private address") {
+		t.Fatalf("Expected a private target failure, got %+v", store.results)
+	}
+}
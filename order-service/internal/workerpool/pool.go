@@ -0,0 +1,223 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/metrics"
+)
+
+// Policy decides what Submit does when the queue is full.
+type Policy string
+
+const (
+	// PolicyBlock waits up to BlockTimeout for room in the queue.
+	PolicyBlock Policy = "block"
+	// PolicyShed rejects the item immediately.
+	PolicyShed Policy = "shed"
+	// PolicySpill hands the item to the Spill function, e.g. to persist it
+	// in an outbox that is drained later.
+	PolicySpill Policy = "spill"
+)
+
+// ErrOverloaded is returned by Submit when the item was neither queued nor
+// spilled.
+var ErrOverloaded = errors.New("worker pool overloaded")
+
+type Config struct {
+	Name      string
+	Workers   int
+	QueueSize int
+	Policy    Policy
+	// BlockTimeout bounds the wait of PolicyBlock; zero waits until the
+	// submitter's context is done.
+	BlockTimeout time.Duration
+}
+
+// Validate checks the sizing and policy.
+func (c Config) Validate() error {
+	if c.Workers <= 0 {
+		return fmt.Errorf("%s pool: workers must be positive", c.Name)
+	}
+	if c.QueueSize < 0 {
+		return fmt.Errorf("%s pool: queue size must not be negative", c.Name)
+	}
+	switch c.Policy {
+	case PolicyBlock, PolicyShed, PolicySpill:
+	default:
+		return fmt.Errorf("%s pool: unknown overflow policy %q", c.Name, c.Policy)
+	}
+	return nil
+}
+
+type job[T any] struct {
+	ctx  context.Context
+	item T
+}
+
+// Pool runs handle for submitted items on a fixed number of workers fed by
+// a bounded queue.
+type Pool[T any] struct {
+	cfg    Config
+	handle func(ctx context.Context, item T)
+	spill  func(ctx context.Context, item T) error
+	queue  chan job[T]
+	wg     sync.WaitGroup
+
+	// mutex guards closed so that nothing is sent on queue after Close.
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// New starts the workers. spill is required for PolicySpill and ignored
+// otherwise.
+func New[T any](cfg Config, handle func(ctx context.Context, item T), spill func(ctx context.Context, item T) error) (*Pool[T], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Policy == PolicySpill && spill == nil {
+		return nil, fmt.Errorf("%s pool: spill policy needs a spill function", cfg.Name)
+	}
+
+	p := &Pool[T]{
+		cfg:    cfg,
+		handle: handle,
+		spill:  spill,
+		queue:  make(chan job[T], cfg.QueueSize),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p, nil
+}
+
+func (p *Pool[T]) worker() {
+	defer p.wg.Done()
+	busy := metrics.PoolBusyWorkers.WithLabelValues(p.cfg.Name)
+	for j := range p.queue {
+		busy.Inc()
+		p.run(j)
+		busy.Dec()
+	}
+}
+
+func (p *Pool[T]) run(j job[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Panic in worker pool", "pool", p.cfg.Name, "panic", r)
+		}
+	}()
+	p.handle(j.ctx, j.item)
+}
+
+// Submit queues item for handling, applying the overflow policy when the
+// queue is full. ctx is passed to the handler without its cancellation.
+// After Close, items are handled synchronously.
+func (p *Pool[T]) Submit(ctx context.Context, item T) error {
+	j := job[T]{ctx: context.WithoutCancel(ctx), item: item}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		p.record("inline")
+		p.run(j)
+		return nil
+	}
+
+	select {
+	case p.queue <- j:
+		p.record("queued")
+		return nil
+	default:
+	}
+
+	switch p.cfg.Policy {
+	case PolicySpill:
+		if err := p.spill(j.ctx, item); err != nil {
+			p.record("shed")
+			return fmt.Errorf("%w: spill FAILED: %v", ErrOverloaded, err)
+		}
+		p.record("spilled")
+		return nil
+	case PolicyBlock:
+		return p.block(ctx, j)
+	default:
+		p.record("shed")
+		return ErrOverloaded
+	}
+}
+
+func (p *Pool[T]) block(ctx context.Context, j job[T]) error {
+	start := time.Now()
+	defer func() {
+		metrics.PoolBlockDuration.WithLabelValues(p.cfg.Name).Observe(time.Since(start).Seconds())
+	}()
+
+	var timeout <-chan time.Time
+	if p.cfg.BlockTimeout > 0 {
+		timer := time.NewTimer(p.cfg.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case p.queue <- j:
+		p.record("blocked")
+		return nil
+	case <-timeout:
+		p.record("timeout")
+		return ErrOverloaded
+	case <-ctx.Done():
+		p.record("timeout")
+		return fmt.Errorf("%w: %v", ErrOverloaded, ctx.Err())
+	}
+}
+
+// Saturated reports whether a submission would currently hit the overflow
+// policy. Callers can use it to shed work before doing anything expensive.
+func (p *Pool[T]) Saturated() bool {
+	return len(p.queue) >= cap(p.queue)
+}
+
+// Policy returns the configured overflow policy.
+func (p *Pool[T]) Policy() Policy {
+	return p.cfg.Policy
+}
+
+// RegisterMetrics exposes the queue depth. Call it once per pool name.
+func (p *Pool[T]) RegisterMetrics() {
+	metrics.RegisterQueueDepth(p.cfg.Name,
+		func() int { return len(p.queue) },
+		func() int { return cap(p.queue) })
+}
+
+// Close stops accepting queued work and waits, until ctx is done, for the
+// workers to drain the queue.
+func (p *Pool[T]) Close(ctx context.Context) error {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out draining %s pool: %w", p.cfg.Name, ctx.Err())
+	}
+}
+
+func (p *Pool[T]) record(result string) {
+	metrics.PoolSubmissions.WithLabelValues(p.cfg.Name, result).Inc()
+}
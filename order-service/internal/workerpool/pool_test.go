@@ -0,0 +1,101 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dandiagusm/microservices-product-order/order-service/internal/workerpool"
+)
+
+// stalledPool returns a pool whose single worker is stuck until release is
+// closed and whose queue of one is already full.
+func stalledPool(t *testing.T, policy workerpool.Policy, spill func(context.Context, int) error) (*workerpool.Pool[int], chan struct{}, *atomic.Int32) {
+	t.Helper()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var handled atomic.Int32
+	p, err := workerpool.New(workerpool.Config{
+		Name: "test_" + string(policy), Workers: 1, QueueSize: 1, Policy: policy, BlockTimeout: 20 * time.Millisecond,
+	}, func(ctx context.Context, n int) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		handled.Add(1)
+	}, spill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = p.Submit(ctx, 1)
+	<-started
+	if err := p.Submit(ctx, 2); err != nil {
+		t.Fatalf("Expected second item to be queued: %v", err)
+	}
+	return p, release, &handled
+}
+
+func TestPool_ShedRejectsWhenFull(t *testing.T) {
+	p, release, handled := stalledPool(t, workerpool.PolicyShed, nil)
+	if !p.Saturated() {
+		t.Fatal("Expected pool to be saturated")
+	}
+	if err := p.Submit(context.Background(), 3); !errors.Is(err, workerpool.ErrOverloaded) {
+		t.Fatalf("Expected ErrOverloaded, got %v", err)
+	}
+	close(release)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := handled.Load(); got != 2 {
+		t.Fatalf("Expected 2 handled items, got %d", got)
+	}
+}
+
+func TestPool_BlockTimesOut(t *testing.T) {
+	p, release, _ := stalledPool(t, workerpool.PolicyBlock, nil)
+	start := time.Now()
+	if err := p.Submit(context.Background(), 3); !errors.Is(err, workerpool.ErrOverloaded) {
+		t.Fatalf("Expected ErrOverloaded, got %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("Expected Submit to wait for the block timeout")
+	}
+	close(release)
+	_ = p.Close(context.Background())
+}
+
+func TestPool_SpillWhenFull(t *testing.T) {
+	var spilled []int
+	p, release, _ := stalledPool(t, workerpool.PolicySpill, func(ctx context.Context, n int) error {
+		spilled = append(spilled, n)
+		return nil
+	})
+	if err := p.Submit(context.Background(), 3); err != nil {
+		t.Fatalf("Expected spill to succeed: %v", err)
+	}
+	if len(spilled) != 1 || spilled[0] != 3 {
+		t.Fatalf("Expected item 3 to be spilled, got %v", spilled)
+	}
+	close(release)
+	_ = p.Close(context.Background())
+}
+
+func TestPool_RunsInlineAfterClose(t *testing.T) {
+	var handled atomic.Int32
+	p, err := workerpool.New(workerpool.Config{Name: "test_inline", Workers: 1, QueueSize: 1, Policy: workerpool.PolicyShed},
+		func(ctx context.Context, n int) { handled.Add(1) }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Submit(context.Background(), 1); err != nil || handled.Load() != 1 {
+		t.Fatalf("Expected inline handling after close, err=%v handled=%d", err, handled.Load())
+	}
+}
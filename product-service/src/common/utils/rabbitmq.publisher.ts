@@ -95,7 +95,8 @@ export class RabbitmqPublisher implements OnModuleInit, OnModuleDestroy {
     const consumers = options?.consumers ?? 100;
     const prefetch = options?.prefetch ?? 1000;
     const serviceName = 'product-service';
-    const queueName = `${routingKey}.${serviceName}`;
+    // a trailing wildcard does not change the queue name
+    const queueName = `${routingKey.replace(/\.#$/, '')}.${serviceName}`;
 
     // Ensure queue exists and bind once
     await this.channel.assertQueue(queueName, { durable: true });
@@ -139,7 +139,7 @@ describe('ProductsService', () => {
   describe('onModuleInit', () => {
     it('should subscribe to order.created', async () => {
       await service.onModuleInit();
-      expect(mockPublisher.subscribe).toHaveBeenCalledWith('order.created', expect.any(Function));
+      expect(mockPublisher.subscribe).toHaveBeenCalledWith('order.created.#', expect.any(Function), expect.any(Object));
     });
 
     it('should handle order.created message', async () => {
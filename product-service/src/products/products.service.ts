@@ -25,11 +25,12 @@ export class ProductsService implements OnModuleInit {
   async onModuleInit() {
     await this.publisher.ready;
 
+    // order.created is published as order.created.<tenantId>
     await this.publisher.subscribe(
-      'order.created',
+      'order.created.#',
       async (order: any) => {
         const requestId = order.requestId ?? 'N/A';
-        const { orderId, productId, quantity } = order;
+        const { orderId, productId, quantity, tenantId } = order;
 
         if (!productId || !quantity) {
           this.logger.warn(`[${requestId}] Invalid order message`, order);
@@ -48,9 +49,10 @@ export class ProductsService implements OnModuleInit {
             `[${requestId}] Reduced product ${productId} qty by ${quantity}`
           );
 
-          this.publisher.publish('order.updated', {
+          this.publisher.publish(tenantId ? `order.updated.${tenantId}` : 'order.updated', {
             orderId,
             productId,
+            tenantId,
             status: 'done',
             updatedAt: new Date().toISOString(),
             requestId,
@@ -0,0 +1,45 @@
+#!/bin/sh
+# Creates the role order-service connects as. POSTGRES_USER is a superuser,
+# which bypasses row-level security; APP_DB_USER is not and owns the
+# service's tables, so FORCE ROW LEVEL SECURITY applies to it.
+# DISPATCHER_DB_USER is the role the webhook dispatcher connects as; the
+# service grants it the webhook tables and a policy that spans tenants.
+#
+# Runs from /docker-entrypoint-initdb.d on a fresh volume. It is idempotent,
+# so an existing volume can be moved over with
+#   docker compose exec order-db sh /docker-entrypoint-initdb.d/10-app-role.sh
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+	DO \$\$
+	BEGIN
+		IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '$APP_DB_USER') THEN
+			CREATE ROLE "$APP_DB_USER" LOGIN PASSWORD '$APP_DB_PASSWORD';
+		END IF;
+	END
+	\$\$;
+	ALTER ROLE "$APP_DB_USER" NOSUPERUSER NOBYPASSRLS;
+	GRANT CONNECT, TEMPORARY ON DATABASE "$POSTGRES_DB" TO "$APP_DB_USER";
+	GRANT USAGE, CREATE ON SCHEMA public TO "$APP_DB_USER";
+
+	DO \$\$
+	BEGIN
+		IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '$DISPATCHER_DB_USER') THEN
+			CREATE ROLE "$DISPATCHER_DB_USER" LOGIN PASSWORD '$DISPATCHER_DB_PASSWORD';
+		END IF;
+	END
+	\$\$;
+	ALTER ROLE "$DISPATCHER_DB_USER" NOSUPERUSER NOBYPASSRLS;
+	GRANT CONNECT ON DATABASE "$POSTGRES_DB" TO "$DISPATCHER_DB_USER";
+	GRANT USAGE ON SCHEMA public TO "$DISPATCHER_DB_USER";
+
+	-- tables created by earlier versions, which connected as $POSTGRES_USER
+	DO \$\$
+	DECLARE t record;
+	BEGIN
+		FOR t IN SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tableowner = current_user LOOP
+			EXECUTE format('ALTER TABLE public.%I OWNER TO %I', t.tablename, '$APP_DB_USER');
+		END LOOP;
+	END
+	\$\$;
+EOSQL